@@ -0,0 +1,364 @@
+package bfd
+
+/*
+#include <bfd.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include "gobfd.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ArchiveFormat is the layout of the symbol map and long member names.
+// Which one gets written is decided by the archive routines of the output
+// target, so asking for a format picks a target that writes it.
+type ArchiveFormat int
+
+const (
+	ArchiveDefault ArchiveFormat = iota // whatever the target writes
+	ArchiveGNU                          // "/" symbol map, "//" long name table
+	ArchiveBSD                          // __.SYMDEF symbol map, as a.out and Mach-O targets write
+)
+
+func (f ArchiveFormat) String() string {
+	switch f {
+	case ArchiveGNU:
+		return "gnu"
+	case ArchiveBSD:
+		return "bsd"
+	}
+	return "default"
+}
+
+// ArchiveOptions controls how an archive is written. A Format other than
+// ArchiveDefault replaces the target of the archive being edited, or the
+// default target, with the first configured target of the same byte order
+// that writes that format; a Target that writes another format is an
+// error. Deterministic zeroes the timestamps and owners of the members and
+// gives them mode 0644, like ar -D.
+type ArchiveOptions struct {
+	Target        string
+	Format        ArchiveFormat
+	Thin          bool
+	Deterministic bool
+	NoSymbolMap   bool
+}
+
+// ArchiveWriter edits the member list of an archive, like ar does, and
+// writes the result out on Close.
+type ArchiveWriter struct {
+	name    string
+	opts    ArchiveOptions
+	iarch   *File
+	members []*File
+	opened  []*File
+}
+
+// NewArchiveWriter starts an empty archive, replacing name on Close.
+func NewArchiveWriter(name string, opts *ArchiveOptions) (*ArchiveWriter, error) {
+	w := &ArchiveWriter{name: name}
+	if opts != nil {
+		w.opts = *opts
+	}
+	return w, nil
+}
+
+// OpenArchiveWriter starts from the members of the archive name, or from an
+// empty archive if it does not exist yet. An existing archive keeps its
+// target and stays thin if it was.
+func OpenArchiveWriter(name string, opts *ArchiveOptions) (*ArchiveWriter, error) {
+	w, _ := NewArchiveWriter(name, opts)
+	if _, err := os.Stat(name); os.IsNotExist(err) {
+		return w, nil
+	}
+
+	iarch, err := Openr(name, w.opts.Target)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckFormat(iarch, Archive); err != nil {
+		Close(iarch)
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	w.iarch = iarch
+	if IsThinArchive(iarch) {
+		w.opts.Thin = true
+	}
+
+	var member *File
+	for {
+		member, err = OpenrNextArchivedFile(iarch, member)
+		if err == ErrNoMoreArchivedFiles {
+			break
+		}
+		if err != nil {
+			w.Abort()
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		w.members = append(w.members, member)
+	}
+	return w, nil
+}
+
+// Members returns the member names in archive order.
+func (w *ArchiveWriter) Members() []string {
+	var names []string
+	for _, m := range w.members {
+		names = append(names, memberName(m))
+	}
+	return names
+}
+
+// Append adds the file at path to the end of the archive, even if a member
+// of the same name exists already.
+func (w *ArchiveWriter) Append(path string) error {
+	m, err := w.open(path)
+	if err != nil {
+		return err
+	}
+	w.members = append(w.members, m)
+	return nil
+}
+
+// Replace puts the file at path in place of the member with the same name,
+// appending it if there is none.
+func (w *ArchiveWriter) Replace(path string) error {
+	m, err := w.open(path)
+	if err != nil {
+		return err
+	}
+	if i := w.index(memberName(m)); i >= 0 {
+		w.members[i] = m
+	} else {
+		w.members = append(w.members, m)
+	}
+	return nil
+}
+
+// Delete removes the first member called name.
+func (w *ArchiveWriter) Delete(name string) error {
+	i := w.index(name)
+	if i < 0 {
+		return &os.PathError{Op: "delete", Path: name, Err: os.ErrNotExist}
+	}
+	w.members = append(w.members[:i], w.members[i+1:]...)
+	return nil
+}
+
+func (w *ArchiveWriter) index(name string) int {
+	for i, m := range w.members {
+		if memberName(m) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *ArchiveWriter) open(path string) (*File, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	m, err := Openr(path, "")
+	if err != nil {
+		return nil, err
+	}
+	w.opened = append(w.opened, m)
+	return m, nil
+}
+
+func (w *ArchiveWriter) target() (string, error) {
+	if w.opts.Target != "" {
+		t := FindTarget(w.opts.Target, nil)
+		if t == nil {
+			return "", GetError()
+		}
+		if w.opts.Format != ArchiveDefault && archiveFormat(t) != w.opts.Format {
+			return "", fmt.Errorf("%s: target %s does not write %s archives", w.name, t.Name(), w.opts.Format)
+		}
+		return w.opts.Target, nil
+	}
+
+	var target string
+	t := FindTarget("default", nil)
+	if w.iarch != nil {
+		t = w.iarch.Xvec()
+		target = t.Name()
+	}
+	if w.opts.Format == ArchiveDefault || t == nil || archiveFormat(t) == w.opts.Format {
+		return target, nil
+	}
+	for _, name := range TargetList() {
+		c := FindTarget(name, nil)
+		if c != nil && archiveFormat(c) == w.opts.Format && c.ByteOrder() == t.ByteOrder() {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%s: no target with the byte order of %s writes %s archives", w.name, t.Name(), w.opts.Format)
+}
+
+// Close writes the archive, with a symbol map unless NoSymbolMap is set,
+// and releases the member files.
+func (w *ArchiveWriter) Close() error {
+	defer w.Abort()
+
+	target, err := w.target()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(w.name), "gobfd")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	f.Close()
+
+	obfd, err := Openw(tmp, target)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := SetFormat(obfd, Archive); err != nil {
+		CloseAllDone(obfd)
+		os.Remove(tmp)
+		return err
+	}
+	if w.opts.Deterministic {
+		obfd.SetBfdUseFlags(obfd.Flags() | DETERMINISTIC_OUTPUT)
+	}
+	SetThinArchive(obfd, w.opts.Thin)
+	SetHasMap(obfd, !w.opts.NoSymbolMap)
+
+	var head *File
+	for i := len(w.members) - 1; i >= 0; i-- {
+		w.members[i].archive_next = (*C.bfd)(head)
+		head = w.members[i]
+	}
+	if err := SetArchiveHead(obfd, head); err != nil {
+		CloseAllDone(obfd)
+		os.Remove(tmp)
+		return err
+	}
+	if err := Close(obfd); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("%s: %v", w.name, err)
+	}
+
+	if fi, err := os.Stat(w.name); err == nil {
+		os.Chmod(tmp, fi.Mode().Perm())
+	} else {
+		os.Chmod(tmp, 0644)
+	}
+	return os.Rename(tmp, w.name)
+}
+
+// Abort releases the member files without writing anything.
+func (w *ArchiveWriter) Abort() {
+	for _, m := range w.opened {
+		Close(m)
+	}
+	if w.iarch != nil {
+		Close(w.iarch)
+	}
+	w.opened, w.iarch, w.members = nil, nil, nil
+}
+
+// Ranlib rewrites the archive name with a fresh symbol map.
+func Ranlib(name string, opts *ArchiveOptions) error {
+	w, err := OpenArchiveWriter(name, opts)
+	if err != nil {
+		return err
+	}
+	w.opts.NoSymbolMap = false
+	return w.Close()
+}
+
+// archiveFormat returns the format t writes archives in, or ArchiveDefault
+// if it is neither.
+func archiveFormat(t *Target) ArchiveFormat {
+	switch t.Flavor() {
+	case TargetAoutFlavor, TargetMachoFlavor:
+		return ArchiveBSD
+	case TargetElfFlavor, TargetCoffFlavor:
+		return ArchiveGNU
+	}
+	return ArchiveDefault
+}
+
+func memberName(m *File) string {
+	return filepath.Base(m.Filename())
+}
+
+// ArchiveSymbol is an entry of the archive symbol map.
+type ArchiveSymbol struct {
+	Name   string
+	Member *File
+}
+
+// ReadArmap returns the symbol map of an archive in the order the linker
+// searches it. It fails with ErrNoArmap if the archive has none.
+func ReadArmap(abfd *File) ([]ArchiveSymbol, error) {
+	if GetFormat(abfd) != Archive {
+		if err := CheckFormat(abfd, Archive); err != nil {
+			return nil, err
+		}
+	}
+	if !HasMap(abfd) {
+		return nil, ErrNoArmap
+	}
+
+	var syms []ArchiveSymbol
+	var sym *C.carsym
+	index := ^C.symindex(0)
+	for {
+		index = C.bfd_get_next_mapent((*C.bfd)(abfd), index, &sym)
+		if index == ^C.symindex(0) {
+			break
+		}
+		member := C.getEltAtIndex((*C.bfd)(abfd), index)
+		if member == nil {
+			return nil, GetError()
+		}
+		syms = append(syms, ArchiveSymbol{
+			Name:   C.GoString(sym.name),
+			Member: (*File)(member),
+		})
+	}
+	return syms, nil
+}
+
+// ArchiveMap maps each symbol in the archive symbol map to the member
+// defining it; if several do, the first one is kept, as the linker would.
+func ArchiveMap(abfd *File) (map[string]*File, error) {
+	syms, err := ReadArmap(abfd)
+	if err != nil {
+		return nil, err
+	}
+	armap := make(map[string]*File)
+	for _, s := range syms {
+		if _, found := armap[s.Name]; !found {
+			armap[s.Name] = s.Member
+		}
+	}
+	return armap, nil
+}
+
+// ArchiveMemberFor returns the member the linker would pull in to resolve
+// the undefined symbol name, or nil if no member defines it.
+func ArchiveMemberFor(abfd *File, name string) (*File, error) {
+	syms, err := ReadArmap(abfd)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range syms {
+		if s.Name == name {
+			return s.Member, nil
+		}
+	}
+	return nil, nil
+}
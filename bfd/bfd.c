@@ -1,6 +1,7 @@
 #include <bfd.h>
 #include <stdio.h>
 #include <stdlib.h>
+#include <sys/stat.h>
 #include "gobfd.h"
 
 bfd_vma
@@ -29,6 +30,15 @@ setFileFlags(bfd *abfd, int flags)
 	bfd_set_file_flags(abfd, flags);
 }
 
+void
+setBfdUseFlags(bfd *abfd, int flags)
+{
+	/* bfd_set_file_flags refuses bfds opened for reading and archives,
+	   which is where BFD_DECOMPRESS and friends have to be set */
+	abfd->flags &= ~BFD_FLAGS_FOR_BFD_USE_MASK;
+	abfd->flags |= flags & BFD_FLAGS_FOR_BFD_USE_MASK;
+}
+
 long
 getSymtabUpperBound(bfd *abfd)
 {
@@ -98,4 +108,209 @@ bfd_boolean
 findInlinerInfo(bfd *abfd, const char **filename, const char **function, uint *line)
 {
 	return bfd_find_inliner_info(abfd, filename, function, line);
-}
\ No newline at end of file
+}
+
+bfd_format
+getFormat(bfd *abfd)
+{
+	return bfd_get_format(abfd);
+}
+
+bfd_boolean
+setArchMach(bfd *abfd, enum bfd_architecture arch, unsigned long mach)
+{
+	return bfd_set_arch_mach(abfd, arch, mach);
+}
+
+void
+setSectionVMA(bfd *abfd, asection *section, bfd_vma vma)
+{
+	bfd_set_section_vma(abfd, section, vma);
+}
+
+void
+setSectionAlignment(bfd *abfd, asection *section, unsigned int power)
+{
+	bfd_set_section_alignment(abfd, section, power);
+}
+
+bfd_boolean
+copyPrivateHeaderData(bfd *ibfd, bfd *obfd)
+{
+	return bfd_copy_private_header_data(ibfd, obfd);
+}
+
+bfd_boolean
+copyPrivateBfdData(bfd *ibfd, bfd *obfd)
+{
+	return bfd_copy_private_bfd_data(ibfd, obfd);
+}
+
+bfd_boolean
+copyPrivateSectionData(bfd *ibfd, asection *isection, bfd *obfd, asection *osection)
+{
+	return bfd_copy_private_section_data(ibfd, isection, obfd, osection);
+}
+
+void
+setReloc(bfd *abfd, asection *section, arelent **relocs, unsigned int count)
+{
+	bfd_set_reloc(abfd, section, relocs, count);
+}
+
+asymbol *
+makeEmptySymbol(bfd *abfd)
+{
+	return bfd_make_empty_symbol(abfd);
+}
+
+asection *
+absSectionPtr(void)
+{
+	return bfd_abs_section_ptr;
+}
+
+int
+isUndSection(asection *section)
+{
+	return bfd_is_und_section(section);
+}
+
+int
+isAbsSection(asection *section)
+{
+	return bfd_is_abs_section(section);
+}
+
+int
+isComSection(asection *section)
+{
+	return bfd_is_com_section(section);
+}
+
+int
+hasMap(bfd *abfd)
+{
+	return abfd->has_armap;
+}
+
+void
+setHasMap(bfd *abfd, int has)
+{
+	abfd->has_armap = has != 0;
+}
+
+int
+isThinArchive(bfd *abfd)
+{
+	return abfd->is_thin_archive;
+}
+
+void
+setThinArchive(bfd *abfd, int thin)
+{
+	abfd->is_thin_archive = thin != 0;
+}
+
+bfd *
+getEltAtIndex(bfd *abfd, symindex index)
+{
+	return bfd_get_elt_at_index(abfd, index);
+}
+
+int
+statArchElt(bfd *abfd, long long *size, unsigned int *mode, long long *mtime, int *uid, int *gid)
+{
+	struct stat st;
+	int r;
+
+	r = bfd_stat_arch_elt(abfd, &st);
+	if (r != 0)
+		return r;
+
+	*size = st.st_size;
+	*mode = st.st_mode;
+	*mtime = st.st_mtime;
+	*uid = st.st_uid;
+	*gid = st.st_gid;
+	return 0;
+}
+
+char *
+sprintSymbol(bfd *abfd, asymbol *symbol)
+{
+	char *buf;
+	size_t size;
+	FILE *fp;
+
+	buf = NULL;
+	fp = open_memstream(&buf, &size);
+	if (fp == NULL)
+		return NULL;
+	bfd_print_symbol(abfd, fp, symbol, bfd_print_symbol_all);
+	fclose(fp);
+	return buf;
+}
+
+char *
+sprintPrivateBfdData(bfd *abfd, bfd_boolean *ok)
+{
+	char *buf;
+	size_t size;
+	FILE *fp;
+
+	buf = NULL;
+	*ok = FALSE;
+	fp = open_memstream(&buf, &size);
+	if (fp == NULL)
+		return NULL;
+	*ok = bfd_print_private_bfd_data(abfd, fp);
+	fclose(fp);
+	return buf;
+}
+
+void
+getSymbolInfo(bfd *abfd, asymbol *symbol, symbol_info *info)
+{
+	bfd_get_symbol_info(abfd, symbol, info);
+}
+
+const char *
+getSymbolVersionString(bfd *abfd, asymbol *symbol, bfd_boolean *hidden)
+{
+	return bfd_get_symbol_version_string(abfd, symbol, hidden);
+}
+
+asymbol **
+getSyntheticSymtab(bfd *abfd, long symcount, asymbol **syms, long dynsymcount, asymbol **dynsyms, long *count, asymbol **data)
+{
+	asymbol *ret, **list;
+	long i;
+
+	*data = NULL;
+	*count = bfd_get_synthetic_symtab(abfd, symcount, syms, dynsymcount, dynsyms, &ret);
+	if (*count <= 0)
+		return NULL;
+
+	list = malloc(*count * sizeof(*list));
+	if (list == NULL) {
+		free(ret);
+		*count = -1;
+		bfd_set_error(bfd_error_no_memory);
+		return NULL;
+	}
+	for (i = 0; i < *count; i++)
+		list[i] = &ret[i];
+	*data = ret;
+	return list;
+}
+
+bfd_boolean
+getFullSectionContents(bfd *abfd, asection *section, bfd_byte **buf, bfd_size_type *size)
+{
+	*buf = NULL;
+	*size = bfd_get_section_size(section);
+	if (abfd->direction != write_direction && section->rawsize != 0)
+		*size = section->rawsize;
+	return bfd_get_full_section_contents(abfd, section, buf);
+}
@@ -11,9 +11,11 @@ package bfd
 import "C"
 
 import (
+	"io"
 	"math"
 	"os"
 	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -33,9 +35,17 @@ type (
 	Symbol       C.asymbol
 	SymbolTable  struct {
 		syms  unsafe.Pointer
+		data  unsafe.Pointer
 		size  int64
 		count int64
 	}
+	ArchInfo   C.bfd_arch_info_type
+	Reloc      C.arelent
+	RelocTable struct {
+		relocs unsafe.Pointer
+		size   int64
+		count  int64
+	}
 	sectionHandler struct{ handler func(*File, *Section) }
 )
 
@@ -45,6 +55,13 @@ func (c *File) Xvec() *Target      { return (*Target)(c.xvec) }
 func (c *File) Flags() int         { return int(C.getFileFlags((*C.struct_bfd)(c))) }
 func (c *File) SetFlags(flags int) { C.setFileFlags((*C.struct_bfd)(c), C.int(flags)) }
 
+// SetBfdUseFlags replaces the flags that steer bfd itself rather than
+// describe the file, such as DECOMPRESS or DETERMINISTIC_OUTPUT. Unlike
+// SetFlags it works on files opened for reading and on archives.
+func (c *File) SetBfdUseFlags(flags int) {
+	C.setBfdUseFlags((*C.struct_bfd)(c), C.int(flags))
+}
+
 func (s *Section) Name() string    { return C.GoString(s.name) }
 func (s *Section) LMA() VMA        { return VMA(s.lma) }
 func (s *Section) VMA() VMA        { return VMA(s.vma) }
@@ -52,11 +69,91 @@ func (s *Section) Size() int64     { return int64(s.size) }
 func (s *Section) Next() *Section  { return (*Section)(s.next) }
 func (s *Section) Prev() *Section  { return (*Section)(s.prev) }
 func (s *Section) Flags() Flagword { return Flagword(s.flags) }
+func (s *Section) Alignment() uint { return uint(s.alignment_power) }
+func (s *Section) Entsize() uint   { return uint(s.entsize) }
+func (s *Section) Index() int      { return int(s.index) }
+func (s *Section) Filepos() int64  { return int64(s.filepos) }
 
-func (t *Target) Name() string { return C.GoString(t.name) }
+func (a *ArchInfo) Arch() Architecture  { return Architecture(a.arch) }
+func (a *ArchInfo) Mach() uint64        { return uint64(a.mach) }
+func (a *ArchInfo) Name() string        { return C.GoString(a.printable_name) }
+func (a *ArchInfo) BitsPerAddress() int { return int(a.bits_per_address) }
+
+func (t *Target) Name() string      { return C.GoString(t.name) }
+func (t *Target) Flavor() Flavor    { return Flavor(t.flavour) }
+func (t *Target) ByteOrder() Endian { return Endian(t.byteorder) }
 
 func (s *SymbolTable) Size() int64 { return s.count }
-func (s *SymbolTable) Free()       { C.free(s.syms) }
+func (s *SymbolTable) Free()       { C.free(s.syms); C.free(s.data) }
+
+func (s *SymbolTable) Symbol(i int64) *Symbol {
+	return (*Symbol)((*[math.MaxInt32]*C.asymbol)(s.syms)[i])
+}
+
+// NearestSymbol returns the symbol with the highest address at or below
+// addr in the section holding addr, along with the offset of addr from it.
+func (s *SymbolTable) NearestSymbol(addr VMA) (*Symbol, VMA) {
+	var best *Symbol
+	for i := int64(0); i < s.count; i++ {
+		sym := s.Symbol(i)
+		if sym == nil || sym.section == nil {
+			continue
+		}
+		sec := sym.Section()
+		if addr < sec.VMA() || addr >= sec.VMA()+VMA(sec.Size()) {
+			continue
+		}
+		if sym.Address() <= addr && (best == nil || sym.Address() > best.Address()) {
+			best = sym
+		}
+	}
+	if best == nil {
+		return nil, 0
+	}
+	return best, addr - best.Address()
+}
+
+func (s *Symbol) Name() string      { return C.GoString(s.name) }
+func (s *Symbol) Value() VMA        { return VMA(s.value) }
+func (s *Symbol) Flags() Flagword   { return Flagword(s.flags) }
+func (s *Symbol) Section() *Section { return (*Section)(s.section) }
+func (s *Symbol) File() *File       { return (*File)(s.the_bfd) }
+
+// Address is the value of the symbol plus the address of its section.
+func (s *Symbol) Address() VMA {
+	return VMA(s.section.vma + s.value)
+}
+
+func (r *RelocTable) Size() int64 { return r.count }
+func (r *RelocTable) Free()       { C.free(r.relocs) }
+
+func (r *RelocTable) Reloc(i int64) *Reloc {
+	return (*Reloc)((*[math.MaxInt32]*C.arelent)(r.relocs)[i])
+}
+
+func (r *Reloc) Address() VMA { return VMA(r.address) }
+func (r *Reloc) Addend() VMA  { return VMA(r.addend) }
+
+func (r *Reloc) Symbol() *Symbol {
+	if r.sym_ptr_ptr == nil {
+		return nil
+	}
+	return (*Symbol)(*r.sym_ptr_ptr)
+}
+
+func (r *Reloc) Size() int {
+	if r.howto == nil {
+		return 0
+	}
+	return int(C.bfd_get_reloc_size(r.howto))
+}
+
+func (r *Reloc) Type() string {
+	if r.howto == nil {
+		return ""
+	}
+	return C.GoString(r.howto.name)
+}
 
 func xtrue(cond C.bfd_boolean) error {
 	if cond != 0 {
@@ -65,6 +162,13 @@ func xtrue(cond C.bfd_boolean) error {
 	return Error(C.bfd_get_error())
 }
 
+func cbool(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func stringList(list **C.char) []string {
 	if list == nil {
 		return nil
@@ -141,8 +245,11 @@ func Openr(name, target string) (*File, error) {
 		ctarget = C.CString(target)
 		defer C.free(unsafe.Pointer(ctarget))
 	}
-	bfd := C.bfd_openr(cname, nil)
-	return (*File)(bfd), pathError(name)
+	bfd := C.bfd_openr(cname, ctarget)
+	if bfd == nil {
+		return nil, pathError(name)
+	}
+	return (*File)(bfd), nil
 }
 
 func Openw(name, target string) (*File, error) {
@@ -154,7 +261,10 @@ func Openw(name, target string) (*File, error) {
 		defer C.free(unsafe.Pointer(ctarget))
 	}
 	bfd := C.bfd_openw(cname, ctarget)
-	return (*File)(bfd), pathError(name)
+	if bfd == nil {
+		return nil, pathError(name)
+	}
+	return (*File)(bfd), nil
 }
 
 func Create(name string, tmpl *File) (*File, error) {
@@ -172,12 +282,58 @@ func MakeReadable(abfd *File) error {
 	return xtrue(C.bfd_make_readable((*C.bfd)(abfd)))
 }
 
-func Close(abfd *File) {
-	C.bfd_close((*C.bfd)(abfd))
+func Close(abfd *File) error {
+	return xtrue(C.bfd_close((*C.bfd)(abfd)))
+}
+
+func CloseAllDone(abfd *File) error {
+	return xtrue(C.bfd_close_all_done((*C.bfd)(abfd)))
 }
 
-func CloseAllDone(abfd *File) {
-	C.bfd_close_all_done((*C.bfd)(abfd))
+func GetFormat(abfd *File) Format {
+	return Format(C.getFormat((*C.bfd)(abfd)))
+}
+
+func SetFormat(abfd *File, format Format) error {
+	return xtrue(C.bfd_set_format((*C.bfd)(abfd), C.bfd_format(format)))
+}
+
+func GetArch(abfd *File) Architecture {
+	return Architecture(C.bfd_get_arch((*C.bfd)(abfd)))
+}
+
+func GetMach(abfd *File) uint64 {
+	return uint64(C.bfd_get_mach((*C.bfd)(abfd)))
+}
+
+func SetArchMach(abfd *File, arch Architecture, mach uint64) error {
+	return xtrue(C.setArchMach((*C.bfd)(abfd), C.enum_bfd_architecture(arch), C.ulong(mach)))
+}
+
+func GetArchInfo(abfd *File) *ArchInfo {
+	return (*ArchInfo)(C.bfd_get_arch_info((*C.bfd)(abfd)))
+}
+
+func ScanArch(name string) *ArchInfo {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return (*ArchInfo)(C.bfd_scan_arch(cname))
+}
+
+func ArchList() []string {
+	return stringList(C.bfd_arch_list())
+}
+
+func PrintableName(abfd *File) string {
+	return C.GoString(C.bfd_printable_name((*C.bfd)(abfd)))
+}
+
+func PrintableArchMach(arch Architecture, mach uint64) string {
+	return C.GoString(C.bfd_printable_arch_mach(C.enum_bfd_architecture(arch), C.ulong(mach)))
+}
+
+func ApplicableFileFlags(abfd *File) int {
+	return int(abfd.xvec.object_flags)
 }
 
 func GetArchSize(abfd *File) int {
@@ -216,6 +372,71 @@ func SetSectionSize(abfd *File, sec *Section, size Size) {
 	C.bfd_set_section_size((*C.bfd)(abfd), (*C.asection)(sec), C.bfd_size_type(size))
 }
 
+func SetSectionFlags(abfd *File, sec *Section, flags Flagword) error {
+	return xtrue(C.bfd_set_section_flags((*C.bfd)(abfd), (*C.asection)(sec), C.flagword(flags)))
+}
+
+func SetSectionVMA(abfd *File, sec *Section, vma VMA) {
+	C.setSectionVMA((*C.bfd)(abfd), (*C.asection)(sec), C.bfd_vma(vma))
+}
+
+func SetSectionLMA(abfd *File, sec *Section, lma VMA) {
+	sec.lma = C.bfd_vma(lma)
+}
+
+func SetSectionAlignment(abfd *File, sec *Section, power uint) {
+	C.setSectionAlignment((*C.bfd)(abfd), (*C.asection)(sec), C.uint(power))
+}
+
+func GetSectionContents(abfd *File, sec *Section, buf []byte, offset int64) error {
+	if len(buf) == 0 {
+		return nil
+	}
+	return xtrue(C.bfd_get_section_contents((*C.bfd)(abfd), (*C.asection)(sec), unsafe.Pointer(&buf[0]), C.file_ptr(offset), C.bfd_size_type(len(buf))))
+}
+
+// GetFullSectionContents returns all of sec, decompressed if abfd has
+// DECOMPRESS set. Such sections have their decompressed size, which
+// GetSectionContents refuses to read.
+func GetFullSectionContents(abfd *File, sec *Section) ([]byte, error) {
+	var buf *C.bfd_byte
+	var size C.bfd_size_type
+	if err := xtrue(C.getFullSectionContents((*C.bfd)(abfd), (*C.asection)(sec), &buf, &size)); err != nil {
+		return nil, err
+	}
+	if buf == nil {
+		return nil, nil
+	}
+	defer C.free(unsafe.Pointer(buf))
+	if size > math.MaxInt32 {
+		return nil, ErrFileTooBig
+	}
+	return C.GoBytes(unsafe.Pointer(buf), C.int(size)), nil
+}
+
+func SetSectionContents(abfd *File, sec *Section, buf []byte, offset int64) error {
+	if len(buf) == 0 {
+		return nil
+	}
+	return xtrue(C.bfd_set_section_contents((*C.bfd)(abfd), (*C.asection)(sec), unsafe.Pointer(&buf[0]), C.file_ptr(offset), C.bfd_size_type(len(buf))))
+}
+
+func CopyPrivateHeaderData(ibfd, obfd *File) error {
+	return xtrue(C.copyPrivateHeaderData((*C.bfd)(ibfd), (*C.bfd)(obfd)))
+}
+
+func CopyPrivateBfdData(ibfd, obfd *File) error {
+	return xtrue(C.copyPrivateBfdData((*C.bfd)(ibfd), (*C.bfd)(obfd)))
+}
+
+func CopyPrivateSectionData(ibfd *File, isec *Section, obfd *File, osec *Section) error {
+	return xtrue(C.copyPrivateSectionData((*C.bfd)(ibfd), (*C.asection)(isec), (*C.bfd)(obfd), (*C.asection)(osec)))
+}
+
+func IsSectionCompressed(abfd *File, section *Section) bool {
+	return C.bfd_is_section_compressed((*C.bfd)(abfd), (*C.asection)(section)) != 0
+}
+
 func InitSectionDecompressStatus(abfd *File, section *Section) error {
 	return xtrue(C.bfd_init_section_decompress_status((*C.bfd)(abfd), (*C.asection)(section)))
 }
@@ -283,11 +504,114 @@ func CanonicalizeDynamicSymtab(abfd *File, table *SymbolTable) (int64, error) {
 	return table.count, nil
 }
 
+// GetSyntheticSymtab returns the symbols bfd makes up from the regular and
+// dynamic symbols for code that has none of its own, such as foo@plt for
+// PLT entries. Either table may be nil. The synthetic symbols point back
+// into the tables given, so those must outlive the result.
+func GetSyntheticSymtab(abfd *File, syms, dynsyms *SymbolTable) (*SymbolTable, error) {
+	var symcount, dynsymcount C.long
+	var csyms, cdynsyms **C.asymbol
+	if syms != nil {
+		symcount, csyms = C.long(syms.count), (**C.asymbol)(syms.syms)
+	}
+	if dynsyms != nil {
+		dynsymcount, cdynsyms = C.long(dynsyms.count), (**C.asymbol)(dynsyms.syms)
+	}
+
+	var count C.long
+	var data *C.asymbol
+	list := C.getSyntheticSymtab((*C.bfd)(abfd), symcount, csyms, dynsymcount, cdynsyms, &count, &data)
+	if count < 0 {
+		return nil, GetError()
+	}
+	return &SymbolTable{
+		syms:  unsafe.Pointer(list),
+		data:  unsafe.Pointer(data),
+		size:  int64(count) * int64(unsafe.Sizeof(data)),
+		count: int64(count),
+	}, nil
+}
+
+func SetSymtab(abfd *File, table *SymbolTable) error {
+	if table == nil {
+		return xtrue(C.bfd_set_symtab((*C.bfd)(abfd), nil, 0))
+	}
+	return xtrue(C.bfd_set_symtab((*C.bfd)(abfd), (**C.asymbol)(table.syms), C.uint(table.count)))
+}
+
+func GetRelocUpperBound(abfd *File, sec *Section) int64 {
+	return int64(C.bfd_get_reloc_upper_bound((*C.bfd)(abfd), (*C.asection)(sec)))
+}
+
+func AllocRelocTable(size int64) *RelocTable {
+	return &RelocTable{relocs: C.malloc(C.size_t(size)), size: size}
+}
+
+func CanonicalizeReloc(abfd *File, sec *Section, table *RelocTable, syms *SymbolTable) (int64, error) {
+	var csyms **C.asymbol
+	if syms != nil {
+		csyms = (**C.asymbol)(syms.syms)
+	}
+	table.count = int64(C.bfd_canonicalize_reloc((*C.bfd)(abfd), (*C.asection)(sec), (**C.arelent)(table.relocs), csyms))
+	if table.count < 0 {
+		return 0, GetError()
+	}
+	return table.count, nil
+}
+
+func GetDynamicRelocUpperBound(abfd *File) int64 {
+	return int64(C.bfd_get_dynamic_reloc_upper_bound((*C.bfd)(abfd)))
+}
+
+func CanonicalizeDynamicReloc(abfd *File, table *RelocTable, syms *SymbolTable) (int64, error) {
+	var csyms **C.asymbol
+	if syms != nil {
+		csyms = (**C.asymbol)(syms.syms)
+	}
+	table.count = int64(C.bfd_canonicalize_dynamic_reloc((*C.bfd)(abfd), (**C.arelent)(table.relocs), csyms))
+	if table.count < 0 {
+		return 0, GetError()
+	}
+	return table.count, nil
+}
+
+func SetReloc(abfd *File, sec *Section, table *RelocTable) {
+	if table == nil || table.count == 0 {
+		C.setReloc((*C.bfd)(abfd), (*C.asection)(sec), nil, 0)
+		return
+	}
+	C.setReloc((*C.bfd)(abfd), (*C.asection)(sec), (**C.arelent)(table.relocs), C.uint(table.count))
+}
+
 func FindNearestLineDiscriminator(abfd *File, section *Section, table *SymbolTable, addr VMA) (found bool, filename, function string, line, discriminator int64) {
 	var cfilename, cfunction *C.char
 	var cline, cdiscriminator C.uint
-	cfound := C.findNearestLineDiscriminator((*C.struct_bfd)(abfd), (*C.struct_bfd_section)(section), (**C.struct_bfd_symbol)(table.syms), C.bfd_vma(addr), &cfilename, &cfunction, &cline, &cdiscriminator)
-	return cfound != 0, C.GoString(cfilename), C.GoString(cfunction), int64(cline), int64(discriminator)
+	var syms **C.struct_bfd_symbol
+	if table != nil {
+		syms = (**C.struct_bfd_symbol)(table.syms)
+	}
+	cfound := C.findNearestLineDiscriminator((*C.struct_bfd)(abfd), (*C.struct_bfd_section)(section), syms, C.bfd_vma(addr), &cfilename, &cfunction, &cline, &cdiscriminator)
+	return cfound != 0, C.GoString(cfilename), C.GoString(cfunction), int64(cline), int64(cdiscriminator)
+}
+
+func MakeEmptySymbol(abfd *File) *Symbol {
+	return (*Symbol)(C.makeEmptySymbol((*C.bfd)(abfd)))
+}
+
+func AbsSection() *Section {
+	return (*Section)(C.absSectionPtr())
+}
+
+func IsUndSection(section *Section) bool {
+	return C.isUndSection((*C.asection)(section)) != 0
+}
+
+func IsAbsSection(section *Section) bool {
+	return C.isAbsSection((*C.asection)(section)) != 0
+}
+
+func IsComSection(section *Section) bool {
+	return C.isComSection((*C.asection)(section)) != 0
 }
 
 func GetSectionByName(abfd *File, name string) *Section {
@@ -371,6 +695,58 @@ func PrintfVMA(abfd *File, vma VMA) {
 	C.printfVMA((*C.bfd)(abfd), C.bfd_vma(vma))
 }
 
+// SymbolInfo is what nm shows about a symbol. Type is the nm letter, in
+// upper case for global symbols; the stab fields are only set for
+// debugging symbols of a.out files.
+type SymbolInfo struct {
+	Value     VMA
+	Type      byte
+	Name      string
+	StabType  int
+	StabOther int
+	StabDesc  int
+	StabName  string
+}
+
+func GetSymbolInfo(abfd *File, sym *Symbol) SymbolInfo {
+	var info C.symbol_info
+	C.getSymbolInfo((*C.bfd)(abfd), (*C.asymbol)(sym), &info)
+	return SymbolInfo{
+		Value:     VMA(info.value),
+		Type:      byte(info._type),
+		Name:      C.GoString(info.name),
+		StabType:  int(info.stab_type),
+		StabOther: int(info.stab_other),
+		StabDesc:  int(info.stab_desc),
+		StabName:  C.GoString(info.stab_name),
+	}
+}
+
+// GetSymbolVersionString returns the ELF symbol version of a dynamic
+// symbol, empty if it has none. hidden is set for symbols that only bind by
+// explicit version.
+func GetSymbolVersionString(abfd *File, sym *Symbol) (version string, hidden bool) {
+	var chidden C.bfd_boolean
+	str := C.getSymbolVersionString((*C.bfd)(abfd), (*C.asymbol)(sym), &chidden)
+	return C.GoString(str), chidden != 0
+}
+
+// SprintSymbol formats sym the way objdump -t lists it.
+func SprintSymbol(abfd *File, sym *Symbol) string {
+	str := C.sprintSymbol((*C.bfd)(abfd), (*C.asymbol)(sym))
+	defer C.free(unsafe.Pointer(str))
+	return C.GoString(str)
+}
+
+// SprintPrivateBfdData returns the target specific headers objdump -p
+// shows, such as the program headers and dynamic section of ELF files.
+func SprintPrivateBfdData(abfd *File) (string, error) {
+	var ok C.bfd_boolean
+	str := C.sprintPrivateBfdData((*C.bfd)(abfd), &ok)
+	defer C.free(unsafe.Pointer(str))
+	return C.GoString(str), xtrue(ok)
+}
+
 func GetSectionSize(section *Section) Size {
 	return Size(C.getSectionSize((*C.asection)(section)))
 }
@@ -394,6 +770,78 @@ func FindInlinerInfo(abfd *File) (found bool, filename, function string, line in
 	return cfound != 0, C.GoString(cfilename), C.GoString(cfunction), int64(cline)
 }
 
+func OpenrNextArchivedFile(archive, previous *File) (*File, error) {
+	next := C.bfd_openr_next_archived_file((*C.bfd)(archive), (*C.bfd)(previous))
+	if next == nil {
+		return nil, GetError()
+	}
+	return (*File)(next), nil
+}
+
+func SetArchiveHead(output, head *File) error {
+	return xtrue(C.bfd_set_archive_head((*C.bfd)(output), (*C.bfd)(head)))
+}
+
+// ArchStat is the header of an archive member.
+type ArchStat struct {
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	Uid     int
+	Gid     int
+}
+
+func StatArchElt(abfd *File) (*ArchStat, error) {
+	var size, mtime C.longlong
+	var mode C.uint
+	var uid, gid C.int
+	if C.statArchElt((*C.bfd)(abfd), &size, &mode, &mtime, &uid, &gid) != 0 {
+		return nil, GetError()
+	}
+	return &ArchStat{
+		Size:    int64(size),
+		Mode:    os.FileMode(mode & 0777),
+		ModTime: time.Unix(int64(mtime), 0),
+		Uid:     int(uid),
+		Gid:     int(gid),
+	}, nil
+}
+
+func HasMap(abfd *File) bool {
+	return C.hasMap((*C.bfd)(abfd)) != 0
+}
+
+func SetHasMap(abfd *File, has bool) {
+	C.setHasMap((*C.bfd)(abfd), cbool(has))
+}
+
+func IsThinArchive(abfd *File) bool {
+	return C.isThinArchive((*C.bfd)(abfd)) != 0
+}
+
+func SetThinArchive(abfd *File, thin bool) {
+	C.setThinArchive((*C.bfd)(abfd), cbool(thin))
+}
+
+// ReadAt reads raw bytes from the underlying file; for archive members the
+// offset is relative to the start of the member.
+func (c *File) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if C.bfd_seek((*C.bfd)(c), C.file_ptr(off), C.SEEK_SET) != 0 {
+		return 0, GetError()
+	}
+	n := int(C.bfd_bread(unsafe.Pointer(&p[0]), C.bfd_size_type(len(p)), (*C.bfd)(c)))
+	if n < 0 {
+		return 0, GetError()
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
 type Direction C.enum_bfd_direction
 
 const (
@@ -559,6 +1007,35 @@ const (
 	PLUGIN               = C.BFD_PLUGIN
 	COMPRESS_GABI        = C.BFD_COMPRESS_GABI
 	FLAGS_SAVED          = C.BFD_FLAGS_SAVED
+	FLAGS_FOR_BFD_USE    = C.BFD_FLAGS_FOR_BFD_USE_MASK
+)
+
+const (
+	BSF_NO_FLAGS              = C.BSF_NO_FLAGS
+	BSF_LOCAL                 = C.BSF_LOCAL
+	BSF_GLOBAL                = C.BSF_GLOBAL
+	BSF_EXPORT                = C.BSF_EXPORT
+	BSF_DEBUGGING             = C.BSF_DEBUGGING
+	BSF_FUNCTION              = C.BSF_FUNCTION
+	BSF_KEEP                  = C.BSF_KEEP
+	BSF_ELF_COMMON            = C.BSF_ELF_COMMON
+	BSF_WEAK                  = C.BSF_WEAK
+	BSF_SECTION_SYM           = C.BSF_SECTION_SYM
+	BSF_OLD_COMMON            = C.BSF_OLD_COMMON
+	BSF_NOT_AT_END            = C.BSF_NOT_AT_END
+	BSF_CONSTRUCTOR           = C.BSF_CONSTRUCTOR
+	BSF_WARNING               = C.BSF_WARNING
+	BSF_INDIRECT              = C.BSF_INDIRECT
+	BSF_FILE                  = C.BSF_FILE
+	BSF_DYNAMIC               = C.BSF_DYNAMIC
+	BSF_OBJECT                = C.BSF_OBJECT
+	BSF_DEBUGGING_RELOC       = C.BSF_DEBUGGING_RELOC
+	BSF_THREAD_LOCAL          = C.BSF_THREAD_LOCAL
+	BSF_RELC                  = C.BSF_RELC
+	BSF_SRELC                 = C.BSF_SRELC
+	BSF_SYNTHETIC             = C.BSF_SYNTHETIC
+	BSF_GNU_INDIRECT_FUNCTION = C.BSF_GNU_INDIRECT_FUNCTION
+	BSF_GNU_UNIQUE            = C.BSF_GNU_UNIQUE
 )
 
 type Architecture C.enum_bfd_architecture
@@ -0,0 +1,585 @@
+package bfd
+
+/*
+#include <bfd.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// CompressMode selects what happens to .debug_* sections when copying.
+type CompressMode int
+
+const (
+	CompressNone CompressMode = iota // leave debug sections as they are
+	CompressGNU                      // zlib-gnu, renamed to .zdebug_*
+	CompressGABI                     // zlib-gabi, marked SHF_COMPRESSED
+	Decompress                       // write debug sections uncompressed
+)
+
+type CopyOptions struct {
+	InputTarget   string
+	OutputTarget  string
+	DebugSections CompressMode
+
+	// symbol edits, applied in the same order as objcopy: renames and the
+	// prefix first, then the binding changes which match on the new names
+	RedefineSyms      map[string]string
+	PrefixSymbols     string
+	LocalizeSymbols   []string
+	GlobalizeSymbols  []string
+	WeakenSymbols     []string
+	KeepGlobalSymbols []string
+	AddSymbols        []AddSymbol
+
+	// address edits; ChangeAddresses applies to the VMA and LMA of every
+	// section that no SectionChanges entry overrides. The program headers
+	// of linked files are rebuilt to follow the moved sections, like
+	// objcopy does.
+	ChangeAddresses VMA
+	SectionChanges  []SectionChange
+	SetStart        bool
+	StartAddress    VMA
+	ChangeStart     VMA
+}
+
+// SectionContext says which parts of a section a SectionChange edits.
+type SectionContext int
+
+const (
+	SetVMA SectionContext = 1 << iota
+	AlterVMA
+	SetLMA
+	AlterLMA
+	SetAlignment
+)
+
+// SectionChange edits the sections whose names match Pattern, using the
+// syntax of path.Match. Alter adds the value to the address, wrapping
+// around for negative changes, while Set replaces it.
+type SectionChange struct {
+	Pattern   string
+	Context   SectionContext
+	VMA       VMA
+	LMA       VMA
+	Alignment uint
+}
+
+// AddSymbol describes a symbol to add to the output; an empty Section makes
+// it absolute, and it is global unless Flags give it another binding.
+type AddSymbol struct {
+	Name    string
+	Section string
+	Value   VMA
+	Flags   Flagword
+}
+
+// CompressStat is the on-disk size of a debug section before and after a copy.
+type CompressStat struct {
+	Member  string
+	Name    string
+	OutName string
+	Size    Size
+	OutSize Size
+}
+
+func (c CompressStat) Saved() int64 { return int64(c.Size) - int64(c.OutSize) }
+
+func (o *CopyOptions) fileFlags() int {
+	switch o.DebugSections {
+	case CompressGNU:
+		return COMPRESS
+	case CompressGABI:
+		return COMPRESS | COMPRESS_GABI
+	case Decompress:
+		return DECOMPRESS
+	}
+	return 0
+}
+
+func (o *CopyOptions) outputTarget(ibfd *File) string {
+	if o.OutputTarget != "" {
+		return o.OutputTarget
+	}
+	return ibfd.Xvec().Name()
+}
+
+// CopyFile copies the object or archive iname to oname, the way objcopy does.
+func CopyFile(iname, oname string, opts *CopyOptions) error {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+
+	ibfd, err := Openr(iname, opts.InputTarget)
+	if err != nil {
+		return err
+	}
+	defer Close(ibfd)
+
+	// compression has to be requested before the format is checked,
+	// since that is when the section contents get set up
+	ibfd.SetBfdUseFlags(ibfd.Flags() | opts.fileFlags())
+
+	if CheckFormat(ibfd, Archive) == nil {
+		return copyArchive(ibfd, oname, opts)
+	}
+	if _, err := CheckFormatMatches(ibfd, Object); err != nil {
+		return fmt.Errorf("%s: %v", iname, err)
+	}
+	return copyObjectFile(ibfd, oname, opts)
+}
+
+func copyObjectFile(ibfd *File, oname string, opts *CopyOptions) error {
+	obfd, err := Openw(oname, opts.outputTarget(ibfd))
+	if err != nil {
+		return err
+	}
+	if err := Copy(ibfd, obfd, opts); err != nil {
+		CloseAllDone(obfd)
+		os.Remove(oname)
+		return fmt.Errorf("%s: %v", ibfd.Filename(), err)
+	}
+	if err := Close(obfd); err != nil {
+		os.Remove(oname)
+		return fmt.Errorf("%s: %v", oname, err)
+	}
+	return nil
+}
+
+// Copy copies the sections, symbols and relocations of the object ibfd into
+// obfd. Debug section compression follows the flags ibfd was opened with, so
+// use CopyFile unless ibfd has already been set up for it.
+func Copy(ibfd, obfd *File, opts *CopyOptions) error {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+
+	if err := SetFormat(obfd, Object); err != nil {
+		return err
+	}
+	start := GetStartAddress(ibfd)
+	if opts.SetStart {
+		start = opts.StartAddress
+	}
+	if err := SetStartAddress(obfd, start+opts.ChangeStart); err != nil {
+		return err
+	}
+	obfd.SetFlags(ibfd.Flags() & ApplicableFileFlags(obfd))
+	obfd.SetBfdUseFlags(obfd.Flags() | ibfd.Flags()&opts.fileFlags())
+
+	arch, mach := GetArch(ibfd), GetMach(ibfd)
+	if err := SetArchMach(obfd, arch, mach); err != nil && arch != ArchUnknown {
+		return err
+	}
+
+	for isec := ibfd.Sections(); isec != nil; isec = isec.Next() {
+		if err := setupSection(ibfd, isec, obfd, opts); err != nil {
+			return fmt.Errorf("%s: %v", isec.Name(), err)
+		}
+	}
+	// the ELF program headers are rebuilt from the output sections, so
+	// they have to be set up first, as objcopy does
+	if err := CopyPrivateHeaderData(ibfd, obfd); err != nil {
+		return err
+	}
+
+	syms, err := slurpSymtab(ibfd, obfd)
+	if err != nil {
+		return err
+	}
+	osyms, err := filterSymbols(obfd, syms, opts)
+	if err != nil {
+		return err
+	}
+	if err := SetSymtab(obfd, osyms); err != nil {
+		return err
+	}
+
+	for isec := ibfd.Sections(); isec != nil; isec = isec.Next() {
+		if err := copyRelocs(ibfd, isec, obfd, syms); err != nil {
+			return fmt.Errorf("%s: %v", isec.Name(), err)
+		}
+	}
+	for isec := ibfd.Sections(); isec != nil; isec = isec.Next() {
+		if err := copySection(ibfd, isec, obfd); err != nil {
+			return fmt.Errorf("%s: %v", isec.Name(), err)
+		}
+	}
+
+	return CopyPrivateBfdData(ibfd, obfd)
+}
+
+func setupSection(ibfd *File, isec *Section, obfd *File, opts *CopyOptions) error {
+	osec := MakeSectionAnywayWithFlags(obfd, isec.Name(), isec.Flags())
+	if osec == nil {
+		return GetError()
+	}
+
+	vma := isec.VMA()
+	if p := opts.findSectionChange(isec.Name(), SetVMA|AlterVMA); p == nil {
+		vma += opts.ChangeAddresses
+	} else if p.Context&SetVMA != 0 {
+		vma = p.VMA
+	} else {
+		vma += p.VMA
+	}
+
+	lma := isec.LMA()
+	if p := opts.findSectionChange(isec.Name(), SetLMA|AlterLMA); p == nil {
+		lma += opts.ChangeAddresses
+	} else if p.Context&SetLMA != 0 {
+		lma = p.LMA
+	} else {
+		lma += p.LMA
+	}
+
+	alignment := isec.Alignment()
+	if p := opts.findSectionChange(isec.Name(), SetAlignment); p != nil {
+		alignment = p.Alignment
+	}
+
+	SetSectionSize(obfd, osec, GetSectionSize(isec))
+	SetSectionVMA(obfd, osec, vma)
+	SetSectionLMA(obfd, osec, lma)
+	SetSectionAlignment(obfd, osec, alignment)
+	osec.entsize = isec.entsize
+
+	isec.output_section = (*C.asection)(osec)
+	isec.output_offset = 0
+
+	return CopyPrivateSectionData(ibfd, isec, obfd, osec)
+}
+
+func (o *CopyOptions) findSectionChange(name string, context SectionContext) *SectionChange {
+	for i := range o.SectionChanges {
+		p := &o.SectionChanges[i]
+		if p.Context&context == 0 {
+			continue
+		}
+		if matched, _ := path.Match(p.Pattern, name); matched {
+			return p
+		}
+	}
+	return nil
+}
+
+// slurpSymtab reads the symbol table of ibfd into memory owned by obfd,
+// which keeps referring to it until it is closed.
+func slurpSymtab(ibfd, obfd *File) (*SymbolTable, error) {
+	storage := GetSymtabUpperBound(ibfd)
+	if storage < 0 {
+		return nil, GetError()
+	}
+	if storage == 0 {
+		return nil, nil
+	}
+	syms := &SymbolTable{
+		syms: C.bfd_alloc((*C.bfd)(obfd), C.bfd_size_type(storage)),
+		size: storage,
+	}
+	if _, err := CanonicalizeSymtab(ibfd, syms); err != nil {
+		return nil, err
+	}
+	return syms, nil
+}
+
+func filterSymbols(obfd *File, syms *SymbolTable, opts *CopyOptions) (*SymbolTable, error) {
+	redefined := make(map[string]string)
+	for from, to := range opts.RedefineSyms {
+		if prev, found := redefined[to]; found {
+			return nil, fmt.Errorf("symbol %s is target of more than one redefinition (%s and %s)", to, prev, from)
+		}
+		redefined[to] = from
+	}
+
+	localize := symbolSet(opts.LocalizeSymbols)
+	globalize := symbolSet(opts.GlobalizeSymbols)
+	weaken := symbolSet(opts.WeakenSymbols)
+	keepGlobal := symbolSet(opts.KeepGlobalSymbols)
+
+	var count int64
+	if syms != nil {
+		count = syms.Size()
+	}
+	for i := int64(0); i < count; i++ {
+		sym := syms.Symbol(i)
+		name := sym.Name()
+		flags := sym.Flags()
+		undefined := IsUndSection(sym.Section())
+
+		if to, found := opts.RedefineSyms[name]; found {
+			name = to
+		}
+		if opts.PrefixSymbols != "" && flags&BSF_SECTION_SYM == 0 {
+			name = opts.PrefixSymbols + name
+		}
+		if name != sym.Name() {
+			sym.name = allocString(obfd, name)
+		}
+
+		if flags&BSF_GLOBAL != 0 && weaken[name] {
+			sym.flags &^= BSF_GLOBAL
+			sym.flags |= BSF_WEAK
+		}
+		if !undefined && flags&(BSF_GLOBAL|BSF_WEAK) != 0 &&
+			(localize[name] || (len(keepGlobal) != 0 && !keepGlobal[name])) {
+			sym.flags &^= BSF_GLOBAL | BSF_WEAK
+			sym.flags |= BSF_LOCAL
+		}
+		if !undefined && flags&BSF_LOCAL != 0 && globalize[name] {
+			sym.flags &^= BSF_LOCAL
+			sym.flags |= BSF_GLOBAL
+		}
+	}
+
+	if len(opts.AddSymbols) == 0 {
+		return syms, nil
+	}
+
+	// the symbol table is a null terminated array of pointers
+	ptrsize := int64(unsafe.Sizeof((*C.asymbol)(nil)))
+	osyms := &SymbolTable{
+		syms:  C.bfd_alloc((*C.bfd)(obfd), C.bfd_size_type((count+int64(len(opts.AddSymbols))+1)*ptrsize)),
+		count: count + int64(len(opts.AddSymbols)),
+	}
+	osyms.size = (osyms.count + 1) * ptrsize
+	list := (*[math.MaxInt32]*C.asymbol)(osyms.syms)
+	for i := int64(0); i < count; i++ {
+		list[i] = (*C.asymbol)(syms.Symbol(i))
+	}
+	for i, add := range opts.AddSymbols {
+		sym, err := makeSymbol(obfd, add)
+		if err != nil {
+			return nil, err
+		}
+		list[count+int64(i)] = (*C.asymbol)(sym)
+	}
+	list[osyms.count] = nil
+	return osyms, nil
+}
+
+func makeSymbol(obfd *File, add AddSymbol) (*Symbol, error) {
+	section := AbsSection()
+	if add.Section != "" {
+		section = GetSectionByName(obfd, add.Section)
+		if section == nil {
+			return nil, fmt.Errorf("cannot find section %s for symbol %s", add.Section, add.Name)
+		}
+	}
+
+	sym := MakeEmptySymbol(obfd)
+	if sym == nil {
+		return nil, GetError()
+	}
+	sym.name = allocString(obfd, add.Name)
+	sym.value = C.symvalue(add.Value)
+	sym.section = (*C.asection)(section)
+	sym.flags = C.flagword(add.Flags)
+	// like objcopy --add-symbol, symbols are global unless told otherwise
+	if add.Flags&(BSF_LOCAL|BSF_GLOBAL|BSF_WEAK|BSF_GNU_UNIQUE) == 0 {
+		sym.flags |= BSF_GLOBAL
+	}
+	return sym, nil
+}
+
+// allocString copies str into memory that lives as long as abfd does.
+func allocString(abfd *File, str string) *C.char {
+	p := C.bfd_alloc((*C.bfd)(abfd), C.bfd_size_type(len(str)+1))
+	buf := (*[math.MaxInt32]byte)(p)[: len(str)+1 : len(str)+1]
+	copy(buf, str)
+	buf[len(str)] = 0
+	return (*C.char)(p)
+}
+
+func symbolSet(names []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+func copyRelocs(ibfd *File, isec *Section, obfd *File, syms *SymbolTable) error {
+	osec := (*Section)(isec.output_section)
+	storage := GetRelocUpperBound(ibfd, isec)
+	if storage < 0 {
+		if err := GetError(); err != ErrInvalidOperation {
+			return err
+		}
+		storage = 0
+	}
+	if storage == 0 {
+		SetReloc(obfd, osec, nil)
+		osec.flags &^= SEC_RELOC
+		return nil
+	}
+
+	relocs := &RelocTable{
+		relocs: C.bfd_alloc((*C.bfd)(obfd), C.bfd_size_type(storage)),
+		size:   storage,
+	}
+	count, err := CanonicalizeReloc(ibfd, isec, relocs, syms)
+	if err != nil {
+		return err
+	}
+	SetReloc(obfd, osec, relocs)
+	if count == 0 {
+		osec.flags &^= SEC_RELOC
+	}
+	return nil
+}
+
+func copySection(ibfd *File, isec *Section, obfd *File) error {
+	osec := (*Section)(isec.output_section)
+	size := GetSectionSize(isec)
+	if size == 0 || isec.Flags()&SEC_HAS_CONTENTS == 0 || osec.Flags()&SEC_HAS_CONTENTS == 0 {
+		return nil
+	}
+
+	// sections being decompressed can only be read whole
+	buf, err := GetFullSectionContents(ibfd, isec)
+	if err != nil {
+		return err
+	}
+	if Size(len(buf)) > size {
+		buf = buf[:size]
+	}
+	return SetSectionContents(obfd, osec, buf, 0)
+}
+
+// copyArchive copies every member of ibfd through a scratch directory and
+// builds a new archive out of the copies.
+func copyArchive(ibfd *File, oname string, opts *CopyOptions) error {
+	dir, err := os.MkdirTemp(filepath.Dir(oname), "gobfd")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := NewArchiveWriter(oname, &ArchiveOptions{Target: opts.outputTarget(ibfd)})
+	if err != nil {
+		return err
+	}
+
+	var this *File
+	for i := 0; ; i++ {
+		this, err = OpenrNextArchivedFile(ibfd, this)
+		if err == ErrNoMoreArchivedFiles {
+			break
+		}
+		if err != nil {
+			w.Abort()
+			return fmt.Errorf("%s: %v", ibfd.Filename(), err)
+		}
+
+		name, err := copyMember(this, filepath.Join(dir, strconv.Itoa(i)), opts)
+		if err == nil {
+			err = w.Append(name)
+		}
+		if err != nil {
+			w.Abort()
+			return err
+		}
+	}
+	// the copies have to stay around until the archive has been written
+	return w.Close()
+}
+
+func copyMember(this *File, dir string, opts *CopyOptions) (string, error) {
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, filepath.Base(this.Filename()))
+
+	if CheckFormat(this, Object) == nil {
+		return name, copyObjectFile(this, name, opts)
+	}
+
+	// not an object bfd understands, carry it over byte for byte
+	buf := make([]byte, GetSize(this))
+	if _, err := this.ReadAt(buf, 0); err != nil {
+		return "", fmt.Errorf("%s: %v", this.Filename(), err)
+	}
+	return name, os.WriteFile(name, buf, 0644)
+}
+
+// CompareDebugSections reports how the debug sections of iname changed size
+// in oname, matching .zdebug_* sections with their .debug_* counterparts.
+func CompareDebugSections(iname, oname string) ([]CompressStat, error) {
+	ibfd, err := Openr(iname, "")
+	if err != nil {
+		return nil, err
+	}
+	defer Close(ibfd)
+
+	obfd, err := Openr(oname, "")
+	if err != nil {
+		return nil, err
+	}
+	defer Close(obfd)
+
+	if CheckFormat(ibfd, Archive) != nil || CheckFormat(obfd, Archive) != nil {
+		if err := CheckFormat(ibfd, Object); err != nil {
+			return nil, fmt.Errorf("%s: %v", iname, err)
+		}
+		if err := CheckFormat(obfd, Object); err != nil {
+			return nil, fmt.Errorf("%s: %v", oname, err)
+		}
+		return compareDebugSections("", ibfd, obfd), nil
+	}
+
+	var stats []CompressStat
+	var imember, omember *File
+	for {
+		imember, _ = OpenrNextArchivedFile(ibfd, imember)
+		omember, _ = OpenrNextArchivedFile(obfd, omember)
+		if imember == nil || omember == nil {
+			break
+		}
+		if CheckFormat(imember, Object) != nil || CheckFormat(omember, Object) != nil {
+			continue
+		}
+		stats = append(stats, compareDebugSections(imember.Filename(), imember, omember)...)
+	}
+	return stats, nil
+}
+
+func compareDebugSections(member string, ibfd, obfd *File) []CompressStat {
+	var stats []CompressStat
+	for isec := ibfd.Sections(); isec != nil; isec = isec.Next() {
+		name := debugSectionName(isec.Name())
+		if isec.Flags()&SEC_DEBUGGING == 0 || !strings.HasPrefix(name, ".debug_") {
+			continue
+		}
+		for osec := obfd.Sections(); osec != nil; osec = osec.Next() {
+			if debugSectionName(osec.Name()) == name {
+				stats = append(stats, CompressStat{
+					Member:  member,
+					Name:    isec.Name(),
+					OutName: osec.Name(),
+					Size:    GetSectionSize(isec),
+					OutSize: GetSectionSize(osec),
+				})
+				break
+			}
+		}
+	}
+	return stats
+}
+
+func debugSectionName(name string) string {
+	if strings.HasPrefix(name, ".zdebug_") {
+		return ".debug_" + name[len(".zdebug_"):]
+	}
+	return name
+}
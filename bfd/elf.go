@@ -0,0 +1,380 @@
+package bfd
+
+/*
+#include <bfd.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include "gobfd.h"
+*/
+import "C"
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ElfHeader is the file header of an ELF object, with the extended section
+// numbering of files with many sections already resolved.
+type ElfHeader struct {
+	Ident      [elf.EI_NIDENT]byte
+	Class      elf.Class
+	Data       elf.Data
+	Version    elf.Version
+	OSABI      elf.OSABI
+	ABIVersion uint8
+	Type       elf.Type
+	Machine    elf.Machine
+	Entry      uint64
+	Phoff      uint64
+	Shoff      uint64
+	Flags      uint32
+	Ehsize     uint16
+	Phentsize  uint16
+	Phnum      int
+	Shentsize  uint16
+	Shnum      int
+	Shstrndx   int
+}
+
+// ElfSectionHeader is a section header as found in the file. Section is
+// the bfd section made from it, nil for the ones bfd keeps to itself such
+// as the symbol and relocation tables.
+type ElfSectionHeader struct {
+	Index     int
+	Name      string
+	Type      elf.SectionType
+	Flags     elf.SectionFlag
+	Addr      uint64
+	Offset    uint64
+	Size      uint64
+	Link      uint32
+	Info      uint32
+	Addralign uint64
+	Entsize   uint64
+	Section   *Section
+}
+
+type ElfProgramHeader struct {
+	Type   elf.ProgType
+	Flags  elf.ProgFlag
+	Offset uint64
+	Vaddr  uint64
+	Paddr  uint64
+	Filesz uint64
+	Memsz  uint64
+	Align  uint64
+}
+
+func isElf(abfd *File) error {
+	if GetFlavor(abfd) != TargetElfFlavor {
+		return ErrWrongObjectFormat
+	}
+	return nil
+}
+
+// elfByteOrder returns the byte order the file header says the file is in.
+func elfByteOrder(hdr *ElfHeader) binary.ByteOrder {
+	if hdr.Data == elf.ELFDATA2MSB {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+func GetElfHeader(abfd *File) (*ElfHeader, error) {
+	if err := isElf(abfd); err != nil {
+		return nil, err
+	}
+
+	var ident [elf.EI_NIDENT]byte
+	if _, err := abfd.ReadAt(ident[:], 0); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(ident[:elf.EI_CLASS], []byte(elf.ELFMAG)) {
+		return nil, fmt.Errorf("%s: bad ELF magic", abfd.Filename())
+	}
+
+	hdr := &ElfHeader{
+		Ident:      ident,
+		Class:      elf.Class(ident[elf.EI_CLASS]),
+		Data:       elf.Data(ident[elf.EI_DATA]),
+		OSABI:      elf.OSABI(ident[elf.EI_OSABI]),
+		ABIVersion: ident[elf.EI_ABIVERSION],
+	}
+	order := elfByteOrder(hdr)
+	r := io.NewSectionReader(abfd, 0, math.MaxInt64)
+
+	var shnum, shstrndx uint16
+	switch hdr.Class {
+	case elf.ELFCLASS32:
+		var h elf.Header32
+		if err := binary.Read(r, order, &h); err != nil {
+			return nil, err
+		}
+		hdr.Type, hdr.Machine, hdr.Version = elf.Type(h.Type), elf.Machine(h.Machine), elf.Version(h.Version)
+		hdr.Entry, hdr.Phoff, hdr.Shoff = uint64(h.Entry), uint64(h.Phoff), uint64(h.Shoff)
+		hdr.Flags, hdr.Ehsize = h.Flags, h.Ehsize
+		hdr.Phentsize, hdr.Phnum = h.Phentsize, int(h.Phnum)
+		hdr.Shentsize, shnum, shstrndx = h.Shentsize, h.Shnum, h.Shstrndx
+	case elf.ELFCLASS64:
+		var h elf.Header64
+		if err := binary.Read(r, order, &h); err != nil {
+			return nil, err
+		}
+		hdr.Type, hdr.Machine, hdr.Version = elf.Type(h.Type), elf.Machine(h.Machine), elf.Version(h.Version)
+		hdr.Entry, hdr.Phoff, hdr.Shoff = h.Entry, h.Phoff, h.Shoff
+		hdr.Flags, hdr.Ehsize = h.Flags, h.Ehsize
+		hdr.Phentsize, hdr.Phnum = h.Phentsize, int(h.Phnum)
+		hdr.Shentsize, shnum, shstrndx = h.Shentsize, h.Shnum, h.Shstrndx
+	default:
+		return nil, fmt.Errorf("%s: unknown ELF class %d", abfd.Filename(), hdr.Class)
+	}
+	hdr.Shnum, hdr.Shstrndx = int(shnum), int(shstrndx)
+
+	// with more sections than fit in the header, the real counts live in
+	// the otherwise unused section header 0
+	if hdr.Shoff != 0 && (shnum == 0 || shstrndx == uint16(elf.SHN_XINDEX)) {
+		s0, _, err := readElfSectionHeader(abfd, hdr, 0)
+		if err != nil {
+			return nil, err
+		}
+		if shnum == 0 {
+			hdr.Shnum = int(s0.Size)
+		}
+		if shstrndx == uint16(elf.SHN_XINDEX) {
+			hdr.Shstrndx = int(s0.Link)
+		}
+	}
+	return hdr, nil
+}
+
+// readElfSectionHeader reads section header index, returning it along with
+// the offset of its name in the section name string table.
+func readElfSectionHeader(abfd *File, hdr *ElfHeader, index int) (*ElfSectionHeader, uint32, error) {
+	order := elfByteOrder(hdr)
+	off := int64(hdr.Shoff) + int64(index)*int64(hdr.Shentsize)
+	r := io.NewSectionReader(abfd, off, int64(hdr.Shentsize))
+
+	var name uint32
+	sh := &ElfSectionHeader{Index: index}
+	switch hdr.Class {
+	case elf.ELFCLASS32:
+		var s elf.Section32
+		if err := binary.Read(r, order, &s); err != nil {
+			return nil, 0, err
+		}
+		name, sh.Type, sh.Flags = s.Name, elf.SectionType(s.Type), elf.SectionFlag(s.Flags)
+		sh.Addr, sh.Offset, sh.Size = uint64(s.Addr), uint64(s.Off), uint64(s.Size)
+		sh.Link, sh.Info = s.Link, s.Info
+		sh.Addralign, sh.Entsize = uint64(s.Addralign), uint64(s.Entsize)
+	default:
+		var s elf.Section64
+		if err := binary.Read(r, order, &s); err != nil {
+			return nil, 0, err
+		}
+		name, sh.Type, sh.Flags = s.Name, elf.SectionType(s.Type), elf.SectionFlag(s.Flags)
+		sh.Addr, sh.Offset, sh.Size = s.Addr, s.Off, s.Size
+		sh.Link, sh.Info = s.Link, s.Info
+		sh.Addralign, sh.Entsize = s.Addralign, s.Entsize
+	}
+	return sh, name, nil
+}
+
+// GetElfSectionHeaders reads the section header table, including the null
+// section at index 0.
+func GetElfSectionHeaders(abfd *File) ([]*ElfSectionHeader, error) {
+	hdr, err := GetElfHeader(abfd)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Shoff == 0 || hdr.Shnum == 0 {
+		return nil, nil
+	}
+
+	sections := make([]*ElfSectionHeader, hdr.Shnum)
+	names := make([]uint32, hdr.Shnum)
+	for i := range sections {
+		sh, name, err := readElfSectionHeader(abfd, hdr, i)
+		if err != nil {
+			return nil, err
+		}
+		sections[i], names[i] = sh, name
+	}
+
+	var strtab []byte
+	if hdr.Shstrndx > 0 && hdr.Shstrndx < len(sections) {
+		s := sections[hdr.Shstrndx]
+		strtab = make([]byte, s.Size)
+		if _, err := abfd.ReadAt(strtab, int64(s.Offset)); err != nil {
+			return nil, err
+		}
+	}
+	for i, sh := range sections {
+		sh.Name = cstring(strtab, names[i])
+		if i == 0 {
+			continue
+		}
+		for s := abfd.Sections(); s != nil; s = s.Next() {
+			if s.Name() == sh.Name && uint64(s.Filepos()) == sh.Offset {
+				sh.Section = s
+				break
+			}
+		}
+	}
+	return sections, nil
+}
+
+// cstring returns the NUL terminated string at off in a string table.
+func cstring(strtab []byte, off uint32) string {
+	if int64(off) >= int64(len(strtab)) {
+		return ""
+	}
+	str := strtab[off:]
+	if i := bytes.IndexByte(str, 0); i >= 0 {
+		str = str[:i]
+	}
+	return string(str)
+}
+
+// GetElfPhdrs returns the program headers, empty for relocatable objects.
+func GetElfPhdrs(abfd *File) ([]ElfProgramHeader, error) {
+	if err := isElf(abfd); err != nil {
+		return nil, err
+	}
+
+	size := C.bfd_get_elf_phdr_upper_bound((*C.bfd)(abfd))
+	if size < 0 {
+		return nil, GetError()
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := C.malloc(C.size_t(size))
+	defer C.free(buf)
+	count := int(C.bfd_get_elf_phdrs((*C.bfd)(abfd), buf))
+	if count < 0 {
+		return nil, GetError()
+	}
+
+	phdrs := make([]ElfProgramHeader, count)
+	list := (*[math.MaxInt32 / C.sizeof_goElfPhdr]C.goElfPhdr)(buf)[:count:count]
+	for i, p := range list {
+		phdrs[i] = ElfProgramHeader{
+			Type:   elf.ProgType(p.p_type),
+			Flags:  elf.ProgFlag(p.p_flags),
+			Offset: uint64(p.p_offset),
+			Vaddr:  uint64(p.p_vaddr),
+			Paddr:  uint64(p.p_paddr),
+			Filesz: uint64(p.p_filesz),
+			Memsz:  uint64(p.p_memsz),
+			Align:  uint64(p.p_align),
+		}
+	}
+	return phdrs, nil
+}
+
+// Contains reports whether section s lies in the segment, by the same
+// rules readelf uses for its section to segment mapping: .tbss only counts
+// for PT_TLS, and otherwise ELF_SECTION_IN_SEGMENT_STRICT decides.
+func (p *ElfProgramHeader) Contains(s *ElfSectionHeader) bool {
+	return !elfTbssSpecial(s, p) && elfSectionInSegment(s, p, true, true)
+}
+
+// elfTbssSpecial is ELF_TBSS_SPECIAL: .tbss takes neither memory nor file
+// space in segments other than PT_TLS.
+func elfTbssSpecial(s *ElfSectionHeader, p *ElfProgramHeader) bool {
+	return s.Flags&elf.SHF_TLS != 0 && s.Type == elf.SHT_NOBITS && p.Type != elf.PT_TLS
+}
+
+func elfSectionSize(s *ElfSectionHeader, p *ElfProgramHeader) uint64 {
+	if elfTbssSpecial(s, p) {
+		return 0
+	}
+	return s.Size
+}
+
+// elfSectionInSegment is ELF_SECTION_IN_SEGMENT_1 from elf/internal.h. With
+// checkVMA the addresses of SHF_ALLOC sections have to be in the segment
+// too; with strict an empty section at the end of a segment is left for
+// whatever follows, unless the segment is empty as well. The arithmetic
+// wraps around like the unsigned C it comes from.
+func elfSectionInSegment(s *ElfSectionHeader, p *ElfProgramHeader, checkVMA, strict bool) bool {
+	tls := s.Flags&elf.SHF_TLS != 0
+	alloc := s.Flags&elf.SHF_ALLOC != 0
+	nobits := s.Type == elf.SHT_NOBITS
+
+	// only PT_LOAD, PT_GNU_RELRO and PT_TLS segments can contain SHF_TLS
+	// sections, PT_TLS nothing else and PT_PHDR no sections at all
+	if tls {
+		if p.Type != elf.PT_TLS && p.Type != elf.PT_GNU_RELRO && p.Type != elf.PT_LOAD {
+			return false
+		}
+	} else if p.Type == elf.PT_TLS || p.Type == elf.PT_PHDR {
+		return false
+	}
+
+	// PT_LOAD and similar segments only have SHF_ALLOC sections
+	if !alloc {
+		switch {
+		case p.Type == elf.PT_LOAD, p.Type == elf.PT_DYNAMIC, p.Type == elf.PT_GNU_EH_FRAME,
+			p.Type == elf.PT_GNU_STACK, p.Type == elf.PT_GNU_RELRO,
+			p.Type >= elf.PT_GNU_MBIND_LO && p.Type <= elf.PT_GNU_MBIND_HI:
+			return false
+		}
+	}
+
+	// any section besides SHT_NOBITS ones must have its file offset
+	// within the segment
+	if !nobits {
+		if s.Offset < p.Offset {
+			return false
+		}
+		if strict && s.Offset-p.Offset > p.Filesz-1 {
+			return false
+		}
+		if s.Offset-p.Offset+elfSectionSize(s, p) > p.Filesz {
+			return false
+		}
+	}
+
+	// SHF_ALLOC sections must have their address within the segment
+	if checkVMA && alloc {
+		if s.Addr < p.Vaddr {
+			return false
+		}
+		if strict && s.Addr-p.Vaddr > p.Memsz-1 {
+			return false
+		}
+		if s.Addr-p.Vaddr+elfSectionSize(s, p) > p.Memsz {
+			return false
+		}
+	}
+
+	// no empty sections at the start or end of PT_DYNAMIC and PT_NOTE
+	if (p.Type == elf.PT_DYNAMIC || p.Type == elf.PT_NOTE) && s.Size == 0 && p.Memsz != 0 {
+		if !nobits && (s.Offset <= p.Offset || s.Offset-p.Offset >= p.Filesz) {
+			return false
+		}
+		if alloc && (s.Addr <= p.Vaddr || s.Addr-p.Vaddr >= p.Memsz) {
+			return false
+		}
+	}
+	return true
+}
+
+// ElfSegmentMap lists the sections in each segment, in program header order.
+func ElfSegmentMap(phdrs []ElfProgramHeader, sections []*ElfSectionHeader) [][]*ElfSectionHeader {
+	segments := make([][]*ElfSectionHeader, len(phdrs))
+	for i := range phdrs {
+		for _, s := range sections {
+			// like readelf, leave out the null section
+			if s.Index != 0 && phdrs[i].Contains(s) {
+				segments[i] = append(segments[i], s)
+			}
+		}
+	}
+	return segments
+}
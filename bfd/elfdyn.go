@@ -0,0 +1,269 @@
+package bfd
+
+/*
+#include <bfd.h>
+*/
+import "C"
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ElfDyn is an entry of the dynamic section.
+type ElfDyn struct {
+	Tag elf.DynTag
+	Val uint64
+}
+
+// ElfDynamic is the decoded dynamic section of an executable or shared
+// object. The init and fini arrays hold the pointers as stored in the file;
+// in position independent files they are usually filled in by relative
+// relocations at load time, so they can read as zero.
+type ElfDynamic struct {
+	Entries      []ElfDyn
+	Needed       []string
+	Soname       string
+	Rpath        []string
+	Runpath      []string
+	Flags        elf.DynFlag
+	Flags1       elf.DynFlag1
+	Init         uint64
+	Fini         uint64
+	InitArray    []uint64
+	FiniArray    []uint64
+	PreinitArray []uint64
+}
+
+// ElfGetBfdNeededList returns the DT_NEEDED libraries of abfd as bfd reads
+// them.
+func ElfGetBfdNeededList(abfd *File) ([]string, error) {
+	var list *C.struct_bfd_link_needed_list
+	if err := xtrue(C.bfd_elf_get_bfd_needed_list((*C.bfd)(abfd), &list)); err != nil {
+		return nil, err
+	}
+	var needed []string
+	for ; list != nil; list = list.next {
+		needed = append(needed, C.GoString(list.name))
+	}
+	return needed, nil
+}
+
+// ElfGetDtSoname returns the DT_SONAME bfd recorded for a shared object
+// loaded by the linker, empty otherwise; GetElfDynamic reads it from any
+// file.
+func ElfGetDtSoname(abfd *File) string {
+	return C.GoString(C.bfd_elf_get_dt_soname((*C.bfd)(abfd)))
+}
+
+// elfImage reads an ELF file by file offset or by virtual address.
+type elfImage struct {
+	abfd     *File
+	hdr      *ElfHeader
+	order    binary.ByteOrder
+	phdrs    []ElfProgramHeader
+	sections []*ElfSectionHeader
+}
+
+func newElfImage(abfd *File) (*elfImage, error) {
+	hdr, err := GetElfHeader(abfd)
+	if err != nil {
+		return nil, err
+	}
+	phdrs, err := GetElfPhdrs(abfd)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := GetElfSectionHeaders(abfd)
+	if err != nil {
+		return nil, err
+	}
+	return &elfImage{
+		abfd:     abfd,
+		hdr:      hdr,
+		order:    elfByteOrder(hdr),
+		phdrs:    phdrs,
+		sections: sections,
+	}, nil
+}
+
+// offset maps a virtual address to its file offset through the load
+// segments.
+func (m *elfImage) offset(vaddr uint64) (int64, bool) {
+	for _, p := range m.phdrs {
+		if p.Type == elf.PT_LOAD && vaddr >= p.Vaddr && vaddr-p.Vaddr < p.Filesz {
+			return int64(p.Offset + vaddr - p.Vaddr), true
+		}
+	}
+	return 0, false
+}
+
+func (m *elfImage) readAt(off int64, size uint64) ([]byte, error) {
+	buf := make([]byte, size)
+	if _, err := m.abfd.ReadAt(buf, off); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (m *elfImage) readVaddr(vaddr, size uint64) ([]byte, error) {
+	off, ok := m.offset(vaddr)
+	if !ok {
+		return nil, fmt.Errorf("%s: address %#x is not in the file", m.abfd.Filename(), vaddr)
+	}
+	return m.readAt(off, size)
+}
+
+// words decodes buf as an array of class sized words.
+func (m *elfImage) words(buf []byte) []uint64 {
+	var list []uint64
+	if m.hdr.Class == elf.ELFCLASS32 {
+		for ; len(buf) >= 4; buf = buf[4:] {
+			list = append(list, uint64(m.order.Uint32(buf)))
+		}
+	} else {
+		for ; len(buf) >= 8; buf = buf[8:] {
+			list = append(list, m.order.Uint64(buf))
+		}
+	}
+	return list
+}
+
+// section returns the first section header of the given type.
+func (m *elfImage) section(typ elf.SectionType) *ElfSectionHeader {
+	for _, s := range m.sections {
+		if s.Type == typ {
+			return s
+		}
+	}
+	return nil
+}
+
+// dynamicRange locates the dynamic entries, from the section header if
+// there is one or else from the PT_DYNAMIC segment.
+func (m *elfImage) dynamicRange() (off int64, size uint64) {
+	if s := m.section(elf.SHT_DYNAMIC); s != nil {
+		return int64(s.Offset), s.Size
+	}
+	for _, p := range m.phdrs {
+		if p.Type == elf.PT_DYNAMIC {
+			return int64(p.Offset), p.Filesz
+		}
+	}
+	return 0, 0
+}
+
+// dynamic reads the raw dynamic entries.
+func (m *elfImage) dynamic() ([]ElfDyn, error) {
+	off, size := m.dynamicRange()
+	if size == 0 {
+		return nil, nil
+	}
+	buf, err := m.readAt(off, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var dyns []ElfDyn
+	words := m.words(buf)
+	for i := 0; i+1 < len(words); i += 2 {
+		tag := elf.DynTag(words[i])
+		if m.hdr.Class == elf.ELFCLASS32 {
+			tag = elf.DynTag(int32(words[i]))
+		}
+		if tag == elf.DT_NULL {
+			break
+		}
+		dyns = append(dyns, ElfDyn{Tag: tag, Val: words[i+1]})
+	}
+	return dyns, nil
+}
+
+func (m *elfImage) dynstr(dyns []ElfDyn) ([]byte, error) {
+	var addr, size uint64
+	for _, d := range dyns {
+		switch d.Tag {
+		case elf.DT_STRTAB:
+			addr = d.Val
+		case elf.DT_STRSZ:
+			size = d.Val
+		}
+	}
+	if addr != 0 && size != 0 {
+		if buf, err := m.readVaddr(addr, size); err == nil {
+			return buf, nil
+		}
+	}
+	// no usable DT_STRTAB, fall back on the section linked from .dynamic
+	if s := m.section(elf.SHT_DYNAMIC); s != nil && int(s.Link) < len(m.sections) {
+		strtab := m.sections[s.Link]
+		return m.readAt(int64(strtab.Offset), strtab.Size)
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// GetElfDynamic decodes the dynamic section, returning nil if there is none.
+func GetElfDynamic(abfd *File) (*ElfDynamic, error) {
+	m, err := newElfImage(abfd)
+	if err != nil {
+		return nil, err
+	}
+	dyns, err := m.dynamic()
+	if err != nil || dyns == nil {
+		return nil, err
+	}
+	strtab, err := m.dynstr(dyns)
+	if err != nil {
+		return nil, err
+	}
+
+	dyn := &ElfDynamic{Entries: dyns}
+	arrays := make(map[elf.DynTag]uint64)
+	for _, d := range dyns {
+		switch d.Tag {
+		case elf.DT_NEEDED:
+			dyn.Needed = append(dyn.Needed, cstring(strtab, uint32(d.Val)))
+		case elf.DT_SONAME:
+			dyn.Soname = cstring(strtab, uint32(d.Val))
+		case elf.DT_RPATH:
+			dyn.Rpath = append(dyn.Rpath, strings.Split(cstring(strtab, uint32(d.Val)), ":")...)
+		case elf.DT_RUNPATH:
+			dyn.Runpath = append(dyn.Runpath, strings.Split(cstring(strtab, uint32(d.Val)), ":")...)
+		case elf.DT_FLAGS:
+			dyn.Flags = elf.DynFlag(d.Val)
+		case elf.DT_FLAGS_1:
+			dyn.Flags1 = elf.DynFlag1(d.Val)
+		case elf.DT_INIT:
+			dyn.Init = d.Val
+		case elf.DT_FINI:
+			dyn.Fini = d.Val
+		case elf.DT_INIT_ARRAY, elf.DT_INIT_ARRAYSZ,
+			elf.DT_FINI_ARRAY, elf.DT_FINI_ARRAYSZ,
+			elf.DT_PREINIT_ARRAY, elf.DT_PREINIT_ARRAYSZ:
+			arrays[d.Tag] = d.Val
+		}
+	}
+
+	for _, a := range []struct {
+		addr, size elf.DynTag
+		list       *[]uint64
+	}{
+		{elf.DT_INIT_ARRAY, elf.DT_INIT_ARRAYSZ, &dyn.InitArray},
+		{elf.DT_FINI_ARRAY, elf.DT_FINI_ARRAYSZ, &dyn.FiniArray},
+		{elf.DT_PREINIT_ARRAY, elf.DT_PREINIT_ARRAYSZ, &dyn.PreinitArray},
+	} {
+		addr, size := arrays[a.addr], arrays[a.size]
+		if addr == 0 || size == 0 {
+			continue
+		}
+		buf, err := m.readVaddr(addr, size)
+		if err != nil {
+			return nil, err
+		}
+		*a.list = m.words(buf)
+	}
+	return dyn, nil
+}
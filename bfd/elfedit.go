@@ -0,0 +1,185 @@
+package bfd
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ElfEditOptions lists the header changes EditElf makes, the way elfedit
+// does. A non-nil Input field makes EditElf refuse files that do not match
+// it, a non-nil Output field sets the new value. EnableX86Features and
+// DisableX86Features are ElfPropertyX86Feature1 bits to set and clear in
+// the x86 FEATURE_1_AND GNU property.
+type ElfEditOptions struct {
+	InputMachine       *elf.Machine
+	OutputMachine      *elf.Machine
+	InputType          *elf.Type
+	OutputType         *elf.Type
+	InputOSABI         *elf.OSABI
+	OutputOSABI        *elf.OSABI
+	EnableX86Features  uint32
+	DisableX86Features uint32
+}
+
+// file header fields, at the same offsets in both classes
+const (
+	elfTypeOffset    = 16
+	elfMachineOffset = 18
+)
+
+// elfMachineClass is the class of machines that only come in one, so
+// setting them on a file of the other class is a mistake.
+var elfMachineClass = map[elf.Machine]elf.Class{
+	elf.EM_386:     elf.ELFCLASS32,
+	elf.EM_ARM:     elf.ELFCLASS32,
+	elf.EM_PPC:     elf.ELFCLASS32,
+	elf.EM_SPARC:   elf.ELFCLASS32,
+	elf.EM_AARCH64: elf.ELFCLASS64,
+	elf.EM_ALPHA:   elf.ELFCLASS64,
+	elf.EM_IA_64:   elf.ELFCLASS64,
+	elf.EM_PPC64:   elf.ELFCLASS64,
+	elf.EM_SPARCV9: elf.ELFCLASS64,
+	elf.EM_L10M:    elf.ELFCLASS64,
+	elf.EM_K10M:    elf.ELFCLASS64,
+}
+
+// validate checks that the edits make sense for a file with header hdr.
+func (o *ElfEditOptions) validate(name string, hdr *ElfHeader) error {
+	if o.InputMachine != nil && *o.InputMachine != hdr.Machine {
+		return fmt.Errorf("%s: machine is %v, not %v", name, hdr.Machine, *o.InputMachine)
+	}
+	if o.InputType != nil && *o.InputType != hdr.Type {
+		return fmt.Errorf("%s: type is %v, not %v", name, hdr.Type, *o.InputType)
+	}
+	if o.InputOSABI != nil && *o.InputOSABI != hdr.OSABI {
+		return fmt.Errorf("%s: OSABI is %v, not %v", name, hdr.OSABI, *o.InputOSABI)
+	}
+
+	machine := hdr.Machine
+	if o.OutputMachine != nil {
+		machine = *o.OutputMachine
+		if machine == elf.EM_NONE {
+			return fmt.Errorf("%s: cannot set machine to %v", name, machine)
+		}
+		if class, ok := elfMachineClass[machine]; ok && class != hdr.Class {
+			return fmt.Errorf("%s: %v does not fit a %v file", name, machine, hdr.Class)
+		}
+	}
+	if o.OutputType != nil {
+		switch *o.OutputType {
+		case elf.ET_REL, elf.ET_EXEC, elf.ET_DYN:
+		default:
+			return fmt.Errorf("%s: cannot set type to %v", name, *o.OutputType)
+		}
+		if (hdr.Type == elf.ET_REL) != (*o.OutputType == elf.ET_REL) {
+			return fmt.Errorf("%s: cannot change a %v file to %v", name, hdr.Type, *o.OutputType)
+		}
+	}
+	if o.OutputOSABI != nil {
+		osabi := *o.OutputOSABI
+		if !strings.HasPrefix(osabi.String(), "ELFOSABI_") {
+			return fmt.Errorf("%s: unknown OSABI %v", name, osabi)
+		}
+		if osabi == elf.ELFOSABI_ARM && machine != elf.EM_ARM {
+			return fmt.Errorf("%s: %v is only for %v", name, osabi, elf.EM_ARM)
+		}
+	}
+	if o.EnableX86Features|o.DisableX86Features != 0 && machine != elf.EM_386 && machine != elf.EM_X86_64 {
+		return fmt.Errorf("%s: x86 features on a %v file", name, machine)
+	}
+	if o.EnableX86Features&o.DisableX86Features != 0 {
+		return fmt.Errorf("%s: x86 features %#x both enabled and disabled", name, o.EnableX86Features&o.DisableX86Features)
+	}
+	return nil
+}
+
+// EditElf returns the contents of abfd with the file header and x86
+// feature property changed as opts says. Everything is changed in place,
+// so the layout of the file stays the same.
+func EditElf(abfd *File, opts *ElfEditOptions) ([]byte, error) {
+	m, err := newElfImage(abfd)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.validate(abfd.Filename(), m.hdr); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, GetSize(abfd))
+	if _, err := abfd.ReadAt(buf, 0); err != nil {
+		return nil, err
+	}
+
+	if opts.OutputMachine != nil {
+		m.order.PutUint16(buf[elfMachineOffset:], uint16(*opts.OutputMachine))
+	}
+	if opts.OutputType != nil {
+		m.order.PutUint16(buf[elfTypeOffset:], uint16(*opts.OutputType))
+	}
+	if opts.OutputOSABI != nil {
+		buf[elf.EI_OSABI] = byte(*opts.OutputOSABI)
+	}
+	if opts.EnableX86Features|opts.DisableX86Features != 0 {
+		if err := editX86Features(abfd, buf, opts.EnableX86Features, opts.DisableX86Features); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// editX86Features sets and clears bits of the x86 FEATURE_1_AND property.
+// The linker only emits the property when some bit is set, so files
+// without one are refused rather than given a note they have no room for.
+func editX86Features(abfd *File, buf []byte, enable, disable uint32) error {
+	notes, err := GetElfNotes(abfd)
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range notes {
+		n := &notes[i]
+		if !n.is("GNU", ElfNoteGnuProperty) {
+			continue
+		}
+		props, err := n.Properties()
+		if err != nil {
+			return fmt.Errorf("%s: %v", abfd.Filename(), err)
+		}
+		for _, p := range props {
+			if p.Type != ElfPropertyX86Feature1And || len(p.Data) < 4 {
+				continue
+			}
+			bits := n.order.Uint32(p.Data)
+			n.order.PutUint32(buf[p.Offset:], bits&^disable|enable)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s: no x86 feature property", abfd.Filename())
+	}
+	return nil
+}
+
+// EditElfFile applies opts to the file iname and writes the result to
+// oname, which may be iname.
+func EditElfFile(iname, oname string, opts *ElfEditOptions) error {
+	abfd, err := Openr(iname, "")
+	if err != nil {
+		return err
+	}
+	defer Close(abfd)
+
+	if err := CheckFormat(abfd, Object); err != nil {
+		return fmt.Errorf("%s: %v", iname, err)
+	}
+	buf, err := EditElf(abfd, opts)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(iname)
+	if err != nil {
+		return err
+	}
+	return replaceFile(oname, buf, fi.Mode().Perm())
+}
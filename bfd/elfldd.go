@@ -0,0 +1,529 @@
+package bfd
+
+import (
+	"bufio"
+	"debug/elf"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ElfResolveOptions controls how ResolveElfDependencies looks for shared
+// libraries. Sysroot is prepended to every absolute directory searched,
+// except ones made from $ORIGIN, which already point into it, and symbolic
+// links inside it are followed as if it were /. LibraryPath plays the part
+// of LD_LIBRARY_PATH. LdSoConf is the ld.so.conf to read inside the
+// sysroot, /etc/ld.so.conf if empty, and DefaultPaths the trusted
+// directories searched last, /lib and /usr/lib with their 64 bit variants
+// first for 64 bit files if empty.
+type ElfResolveOptions struct {
+	Sysroot      string
+	LibraryPath  []string
+	LdSoConf     string
+	DefaultPaths []string
+	Target       string
+}
+
+// ElfLibrary is an object in the dependency tree. Path is the file found,
+// sysroot included, and is empty for libraries that could not be found.
+// Parent is the object that needed it first.
+type ElfLibrary struct {
+	Name            string
+	Path            string
+	Soname          string
+	Needed          []string
+	Parent          *ElfLibrary
+	Unresolved      []ElfUnresolvedSymbol
+	MissingVersions []ElfMissingVersion
+
+	rpath    []string
+	runpath  []string
+	syms     []ElfSymbol
+	versions *ElfVersions
+	real     string
+}
+
+// ElfUnresolvedSymbol is an undefined symbol nothing loaded defines, with
+// the version and the library it is expected from if it is versioned.
+type ElfUnresolvedSymbol struct {
+	Name    string
+	Version string
+	Library string
+}
+
+// ElfMissingVersion is a version an object requires that the library it
+// requires it from does not define.
+type ElfMissingVersion struct {
+	Library string
+	Version string
+}
+
+// ElfDependencies is the result of resolving the dependencies of a file.
+// Objects are in load order, starting with the file itself and ending with
+// the program interpreter if there is one; Missing are the libraries that
+// were not found.
+type ElfDependencies struct {
+	Objects []*ElfLibrary
+	Missing []*ElfLibrary
+}
+
+// elfResolver walks the dependency tree the way the dynamic linker does,
+// without running anything.
+type elfResolver struct {
+	opts    *ElfResolveOptions
+	class   elf.Class
+	machine elf.Machine
+	conf    []string
+	loaded  map[string]*ElfLibrary
+	deps    ElfDependencies
+}
+
+// ResolveElfDependencies finds the shared libraries name needs, and their
+// libraries in turn, then binds every undefined symbol and version
+// requirement against them.
+func ResolveElfDependencies(name string, opts *ElfResolveOptions) (*ElfDependencies, error) {
+	if opts == nil {
+		opts = &ElfResolveOptions{}
+	}
+	r := &elfResolver{
+		opts:   opts,
+		loaded: make(map[string]*ElfLibrary),
+	}
+	root := &ElfLibrary{Name: name, Path: name}
+	hdr, interp, err := r.read(root)
+	if err != nil {
+		return nil, err
+	}
+	r.class, r.machine = hdr.Class, hdr.Machine
+	r.conf = r.readLdSoConf()
+	r.add(root)
+
+	// breadth first, as the dynamic linker loads them
+	for i := 0; i < len(r.deps.Objects); i++ {
+		obj := r.deps.Objects[i]
+		for _, needed := range obj.Needed {
+			if r.loaded[needed] != nil {
+				continue
+			}
+			lib := &ElfLibrary{Name: needed, Parent: obj}
+			if !r.find(lib) {
+				r.loaded[needed] = lib
+				r.deps.Missing = append(r.deps.Missing, lib)
+				continue
+			}
+			if prev := r.loaded[lib.real]; prev != nil {
+				r.loaded[needed] = prev
+				continue
+			}
+			r.add(lib)
+		}
+	}
+
+	if interp != "" && r.loaded[filepath.Base(interp)] == nil {
+		lib := &ElfLibrary{Name: interp, Path: r.rooted(interp), Parent: root}
+		if _, _, err := r.read(lib); err != nil {
+			lib.Path = ""
+			r.deps.Missing = append(r.deps.Missing, lib)
+		} else if r.loaded[lib.real] == nil {
+			r.add(lib)
+		}
+	}
+
+	r.bindVersions()
+	r.bindSymbols()
+	return &r.deps, nil
+}
+
+func (r *elfResolver) add(lib *ElfLibrary) {
+	r.deps.Objects = append(r.deps.Objects, lib)
+	r.loaded[lib.Name] = lib
+	r.loaded[lib.real] = lib
+	if lib.Soname != "" {
+		r.loaded[lib.Soname] = lib
+	}
+}
+
+// maxSymlinks is how many symbolic links resolve follows before giving up,
+// the same limit as the kernel.
+const maxSymlinks = 40
+
+// resolve follows the symbolic links in path one component at a time, as
+// the dynamic linker would see them with the sysroot as its root: absolute
+// targets start over at the sysroot and .. stops there. Paths outside the
+// sysroot, such as the file being resolved, are taken as they are on the
+// host.
+func (r *elfResolver) resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	root := string(filepath.Separator)
+	if r.opts.Sysroot != "" {
+		sysroot, err := filepath.Abs(r.opts.Sysroot)
+		if err != nil {
+			return "", err
+		}
+		if rel, err := filepath.Rel(sysroot, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			root = sysroot
+		}
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return "", err
+	}
+
+	sep := string(filepath.Separator)
+	pending := strings.Split(rel, sep)
+	var done []string
+	for links := 0; len(pending) > 0; {
+		name := pending[0]
+		pending = pending[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(done) > 0 {
+				done = done[:len(done)-1]
+			}
+			continue
+		}
+
+		target, err := os.Readlink(filepath.Join(root, filepath.Join(append(done, name)...)))
+		if err != nil {
+			// not a link, or missing, which opening it will report
+			done = append(done, name)
+			continue
+		}
+		if links++; links > maxSymlinks {
+			return "", fmt.Errorf("%s: too many levels of symbolic links", path)
+		}
+		if filepath.IsAbs(target) {
+			done = nil
+		}
+		pending = append(strings.Split(target, sep), pending...)
+	}
+	return filepath.Join(root, filepath.Join(done...)), nil
+}
+
+// read resolves lib.Path inside the sysroot and fills in lib from the
+// file it leads to.
+func (r *elfResolver) read(lib *ElfLibrary) (*ElfHeader, string, error) {
+	real, err := r.resolve(lib.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	lib.real = real
+	return readElfLibrary(lib, r.opts.Target)
+}
+
+// readElfLibrary fills in lib from the file at lib.real, the resolved
+// lib.Path, returning its header and program interpreter.
+func readElfLibrary(lib *ElfLibrary, target string) (*ElfHeader, string, error) {
+	abfd, err := Openr(lib.real, target)
+	if err != nil {
+		return nil, "", err
+	}
+	defer Close(abfd)
+	if err := CheckFormat(abfd, Object); err != nil {
+		return nil, "", err
+	}
+
+	m, err := newElfImage(abfd)
+	if err != nil {
+		return nil, "", err
+	}
+	var interp string
+	for _, p := range m.phdrs {
+		if p.Type == elf.PT_INTERP {
+			buf, err := m.readAt(int64(p.Offset), p.Filesz)
+			if err != nil {
+				return nil, "", err
+			}
+			interp = cstring(buf, 0)
+		}
+	}
+
+	dyn, err := GetElfDynamic(abfd)
+	if err != nil {
+		return nil, "", err
+	}
+	if dyn != nil {
+		lib.Soname, lib.Needed = dyn.Soname, dyn.Needed
+		lib.rpath, lib.runpath = dyn.Rpath, dyn.Runpath
+	}
+	if lib.syms, err = GetElfSymbols(abfd, true); err != nil {
+		return nil, "", err
+	}
+	if lib.versions, err = GetElfVersions(abfd); err != nil {
+		return nil, "", err
+	}
+	return m.hdr, interp, nil
+}
+
+// compatible reports whether path is an ELF file the root could load.
+func (r *elfResolver) compatible(path string) bool {
+	real, err := r.resolve(path)
+	if err != nil {
+		return false
+	}
+	abfd, err := Openr(real, r.opts.Target)
+	if err != nil {
+		return false
+	}
+	defer Close(abfd)
+	if CheckFormat(abfd, Object) != nil {
+		return false
+	}
+	hdr, err := GetElfHeader(abfd)
+	return err == nil && hdr.Class == r.class && hdr.Machine == r.machine
+}
+
+func (r *elfResolver) rooted(dir string) string {
+	if r.opts.Sysroot == "" || !filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(r.opts.Sysroot, dir)
+}
+
+// expand substitutes the dynamic string tokens of a search path entry.
+// $ORIGIN is the directory of the object, which already includes the
+// sysroot, so those entries are not rooted again.
+func (r *elfResolver) expand(dir string, obj *ElfLibrary) string {
+	lib := "lib"
+	if r.class == elf.ELFCLASS64 {
+		lib = "lib64"
+	}
+	origin := strings.Contains(dir, "$ORIGIN") || strings.Contains(dir, "${ORIGIN}")
+	dir = strings.NewReplacer(
+		"${ORIGIN}", filepath.Dir(obj.Path), "$ORIGIN", filepath.Dir(obj.Path),
+		"${LIB}", lib, "$LIB", lib,
+	).Replace(dir)
+	if origin {
+		return dir
+	}
+	return r.rooted(dir)
+}
+
+// find searches for lib in the order the dynamic linker does: the DT_RPATH
+// of the objects that led to it when the one needing it has no DT_RUNPATH,
+// the library path, its DT_RUNPATH, ld.so.conf and the default paths.
+func (r *elfResolver) find(lib *ElfLibrary) bool {
+	if strings.Contains(lib.Name, "/") {
+		path := r.rooted(lib.Name)
+		if r.compatible(path) {
+			lib.Path = path
+			_, _, err := r.read(lib)
+			return err == nil
+		}
+		return false
+	}
+
+	var dirs []string
+	if len(lib.Parent.runpath) == 0 {
+		for obj := lib.Parent; obj != nil; obj = obj.Parent {
+			for _, dir := range obj.rpath {
+				dirs = append(dirs, r.expand(dir, obj))
+			}
+		}
+	}
+	for _, dir := range r.opts.LibraryPath {
+		dirs = append(dirs, r.rooted(dir))
+	}
+	for _, dir := range lib.Parent.runpath {
+		dirs = append(dirs, r.expand(dir, lib.Parent))
+	}
+	dirs = append(dirs, r.conf...)
+	for _, dir := range r.defaultPaths() {
+		dirs = append(dirs, r.rooted(dir))
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, lib.Name)
+		if !r.compatible(path) {
+			continue
+		}
+		lib.Path = path
+		if _, _, err := r.read(lib); err == nil {
+			return true
+		}
+	}
+	lib.Path = ""
+	return false
+}
+
+func (r *elfResolver) defaultPaths() []string {
+	if r.opts.DefaultPaths != nil {
+		return r.opts.DefaultPaths
+	}
+	if r.class == elf.ELFCLASS64 {
+		return []string{"/lib64", "/usr/lib64", "/lib", "/usr/lib"}
+	}
+	return []string{"/lib", "/usr/lib"}
+}
+
+// readLdSoConf returns the directories of ld.so.conf and the files it
+// includes, rooted in the sysroot.
+func (r *elfResolver) readLdSoConf() []string {
+	conf := r.opts.LdSoConf
+	if conf == "" {
+		conf = "/etc/ld.so.conf"
+	}
+	var dirs []string
+	seen := make(map[string]bool)
+	var read func(name string)
+	read = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		path, err := r.resolve(r.rooted(name))
+		if err != nil {
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			line := s.Text()
+			if i := strings.IndexByte(line, '#'); i >= 0 {
+				line = line[:i]
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0 || fields[0] == "hwcap":
+			case fields[0] == "include":
+				for _, pattern := range fields[1:] {
+					if !filepath.IsAbs(pattern) {
+						pattern = filepath.Join(filepath.Dir(name), pattern)
+					}
+					matches, _ := filepath.Glob(r.rooted(pattern))
+					for _, match := range matches {
+						if r.opts.Sysroot != "" {
+							match = strings.TrimPrefix(match, filepath.Clean(r.opts.Sysroot))
+						}
+						read(match)
+					}
+				}
+			default:
+				// dir=TYPE and dir:TYPE from the libc5 days name the type
+				// of the libraries in dir
+				for _, dir := range fields {
+					if i := strings.IndexAny(dir, "=:"); i >= 0 {
+						dir = dir[:i]
+					}
+					dirs = append(dirs, r.rooted(dir))
+				}
+			}
+		}
+	}
+	read(conf)
+	return dirs
+}
+
+// definesVersion reports whether lib defines version.
+func definesVersion(lib *ElfLibrary, version string) bool {
+	if lib.versions == nil {
+		return false
+	}
+	for _, d := range lib.versions.Defs {
+		if d.Name == version {
+			return true
+		}
+	}
+	return false
+}
+
+// bindVersions checks the version requirements of every object against
+// the libraries they name.
+func (r *elfResolver) bindVersions() {
+	for _, obj := range r.deps.Objects {
+		if obj.versions == nil {
+			continue
+		}
+		for _, need := range obj.versions.Needs {
+			lib := r.loaded[need.File]
+			if lib == nil || lib.Path == "" {
+				continue
+			}
+			for _, v := range need.Versions {
+				if v.Flags&ElfVerFlagWeak == 0 && !definesVersion(lib, v.Name) {
+					obj.MissingVersions = append(obj.MissingVersions, ElfMissingVersion{Library: need.File, Version: v.Name})
+				}
+			}
+		}
+	}
+}
+
+type elfDefinition struct {
+	lib     *ElfLibrary
+	version string
+	hidden  bool
+}
+
+// bindSymbols looks up the undefined symbols of every object in the global
+// scope. A versioned reference takes a definition of that version, or any
+// from a library without versions; an unversioned one takes the default
+// version.
+func (r *elfResolver) bindSymbols() {
+	defs := make(map[string][]elfDefinition)
+	for _, lib := range r.deps.Objects {
+		for i, sym := range lib.syms {
+			if sym.Section == elf.SHN_UNDEF || sym.Bind == elf.STB_LOCAL || sym.Name == "" {
+				continue
+			}
+			def := elfDefinition{lib: lib}
+			if v := lib.symbolVersion(i); v != nil {
+				def.version, def.hidden = v.Version, v.Hidden
+			}
+			defs[sym.Name] = append(defs[sym.Name], def)
+		}
+	}
+
+	for _, obj := range r.deps.Objects {
+		for i, sym := range obj.syms {
+			if sym.Section != elf.SHN_UNDEF || sym.Bind != elf.STB_GLOBAL || sym.Name == "" {
+				continue
+			}
+			ref := ElfUnresolvedSymbol{Name: sym.Name}
+			if v := obj.symbolVersion(i); v != nil {
+				ref.Version, ref.Library = v.Version, v.Library
+			}
+			if !bindSymbol(defs[sym.Name], ref) {
+				obj.Unresolved = append(obj.Unresolved, ref)
+			}
+		}
+	}
+}
+
+func bindSymbol(defs []elfDefinition, ref ElfUnresolvedSymbol) bool {
+	for _, def := range defs {
+		switch {
+		case ref.Version == "":
+			if !def.hidden {
+				return true
+			}
+		case def.version == ref.Version:
+			return true
+		case def.lib.versions == nil || len(def.lib.versions.Defs) == 0:
+			return true
+		}
+	}
+	return false
+}
+
+// symbolVersion returns the version of dynamic symbol i, which counts from
+// after the null symbol.
+func (lib *ElfLibrary) symbolVersion(i int) *ElfSymbolVersion {
+	if lib.versions == nil || i+1 >= len(lib.versions.Symbols) {
+		return nil
+	}
+	return &lib.versions.Symbols[i+1]
+}
@@ -0,0 +1,348 @@
+package bfd
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Note types, by owner name.
+const (
+	ElfNoteGnuABITag    = 1 // "GNU"
+	ElfNoteGnuHwcap     = 2
+	ElfNoteGnuBuildID   = 3
+	ElfNoteGnuGoldVer   = 4
+	ElfNoteGnuProperty  = 5
+	ElfNoteStapsdt      = 3          // "stapsdt"
+	ElfNoteFdoPackaging = 0xcafe1a7e // "FDO"
+)
+
+// GNU property types. The processor specific ones overlap, so what they
+// mean depends on the machine.
+const (
+	ElfPropertyStackSize          = 1
+	ElfPropertyNoCopyOnProtected  = 2
+	ElfPropertyAArch64Feature1And = 0xc0000000
+	ElfPropertyX86ISA1Used        = 0xc0000000
+	ElfPropertyX86ISA1Needed      = 0xc0000001
+	ElfPropertyX86Feature1And     = 0xc0000002
+	ElfPropertyX86Feature1IBT     = 1 << 0
+	ElfPropertyX86Feature1SHSTK   = 1 << 1
+	ElfPropertyAArch64Feature1BTI = 1 << 0
+	ElfPropertyAArch64Feature1PAC = 1 << 1
+)
+
+// ElfNote is a note from a note section, or from a PT_NOTE segment for files
+// without section headers. Section is empty for the latter. DescOffset is
+// the file offset of Desc.
+type ElfNote struct {
+	Name       string
+	Type       uint32
+	Desc       []byte
+	Section    string
+	DescOffset int64
+
+	order   binary.ByteOrder
+	class   elf.Class
+	machine elf.Machine
+}
+
+// ElfABITag is the oldest kernel an executable runs on, from
+// NT_GNU_ABI_TAG.
+type ElfABITag struct {
+	OS                  string
+	Major, Minor, Patch uint32
+}
+
+// ElfProperty is an entry of a NT_GNU_PROPERTY_TYPE_0 note. Offset is the
+// file offset of Data.
+type ElfProperty struct {
+	Type   uint32
+	Data   []byte
+	Offset int64
+}
+
+// ElfFeatures are the control flow protection features a file is marked as
+// supporting by its GNU properties: x86 indirect branch tracking and shadow
+// stack, AArch64 branch target identification and pointer authentication.
+type ElfFeatures struct {
+	IBT, SHSTK bool
+	BTI, PAC   bool
+}
+
+// ElfStapsdt is a SystemTap probe point. Semaphore is zero for probes
+// without one.
+type ElfStapsdt struct {
+	Provider  string
+	Name      string
+	Args      string
+	PC        uint64
+	Base      uint64
+	Semaphore uint64
+}
+
+// ElfPackage is the package metadata of .note.package, as described by the
+// systemd packaging metadata spec. Raw holds the whole JSON document, for
+// fields beyond the common ones.
+type ElfPackage struct {
+	Type         string          `json:"type"`
+	OS           string          `json:"os"`
+	OSVersion    string          `json:"osVersion"`
+	Name         string          `json:"name"`
+	Version      string          `json:"version"`
+	Architecture string          `json:"architecture"`
+	OSCPE        string          `json:"osCpe"`
+	DebugInfoURL string          `json:"debugInfoUrl"`
+	Raw          json.RawMessage `json:"-"`
+}
+
+// notes parses the notes in size bytes at file offset off. Descriptors are
+// aligned to 8 bytes in notes with that alignment and to 4 otherwise.
+func (m *elfImage) notes(off int64, size, align uint64, section string) ([]ElfNote, error) {
+	buf, err := m.readAt(off, size)
+	if err != nil {
+		return nil, err
+	}
+	pad := uint64(4)
+	if align == 8 {
+		pad = 8
+	}
+	roundup := func(n, a uint64) uint64 { return (n + a - 1) &^ (a - 1) }
+
+	var notes []ElfNote
+	for pos := uint64(0); pos+12 <= uint64(len(buf)); {
+		namesz := uint64(m.order.Uint32(buf[pos:]))
+		descsz := uint64(m.order.Uint32(buf[pos+4:]))
+		typ := m.order.Uint32(buf[pos+8:])
+		name := pos + 12
+		desc := roundup(name+namesz, pad)
+		next := roundup(desc+descsz, pad)
+		if desc+descsz > uint64(len(buf)) || desc < name {
+			return notes, fmt.Errorf("%s: corrupt note at offset %#x", m.abfd.Filename(), uint64(off)+pos)
+		}
+		notes = append(notes, ElfNote{
+			Name:       string(bytes.TrimRight(buf[name:name+namesz], "\x00")),
+			Type:       typ,
+			Desc:       buf[desc : desc+descsz],
+			Section:    section,
+			DescOffset: off + int64(desc),
+			order:      m.order,
+			class:      m.hdr.Class,
+			machine:    m.hdr.Machine,
+		})
+		pos = next
+	}
+	return notes, nil
+}
+
+// GetElfNotes returns the notes of the note sections, or of the PT_NOTE
+// segments if there are no section headers.
+func GetElfNotes(abfd *File) ([]ElfNote, error) {
+	m, err := newElfImage(abfd)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []ElfNote
+	for _, s := range m.sections {
+		if s.Type != elf.SHT_NOTE || s.Size == 0 {
+			continue
+		}
+		list, err := m.notes(int64(s.Offset), s.Size, s.Addralign, s.Name)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, list...)
+	}
+	if len(m.sections) > 0 {
+		return notes, nil
+	}
+
+	for _, p := range m.phdrs {
+		if p.Type != elf.PT_NOTE || p.Filesz == 0 {
+			continue
+		}
+		list, err := m.notes(int64(p.Offset), p.Filesz, p.Align, "")
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, list...)
+	}
+	return notes, nil
+}
+
+func (n *ElfNote) is(name string, typ uint32) bool {
+	return n.Name == name && n.Type == typ
+}
+
+// BuildID returns the hex build id of a NT_GNU_BUILD_ID note.
+func (n *ElfNote) BuildID() (string, bool) {
+	if !n.is("GNU", ElfNoteGnuBuildID) {
+		return "", false
+	}
+	return hex.EncodeToString(n.Desc), true
+}
+
+var elfABITagOS = []string{"Linux", "Hurd", "Solaris", "FreeBSD", "NetBSD", "Syllable", "NaCl"}
+
+// ABITag decodes a NT_GNU_ABI_TAG note.
+func (n *ElfNote) ABITag() (*ElfABITag, bool) {
+	if !n.is("GNU", ElfNoteGnuABITag) || len(n.Desc) < 16 {
+		return nil, false
+	}
+	os := n.order.Uint32(n.Desc)
+	tag := &ElfABITag{
+		OS:    fmt.Sprintf("OS %d", os),
+		Major: n.order.Uint32(n.Desc[4:]),
+		Minor: n.order.Uint32(n.Desc[8:]),
+		Patch: n.order.Uint32(n.Desc[12:]),
+	}
+	if int(os) < len(elfABITagOS) {
+		tag.OS = elfABITagOS[os]
+	}
+	return tag, true
+}
+
+// Properties decodes a NT_GNU_PROPERTY_TYPE_0 note.
+func (n *ElfNote) Properties() ([]ElfProperty, error) {
+	if !n.is("GNU", ElfNoteGnuProperty) {
+		return nil, fmt.Errorf("not a GNU property note")
+	}
+	pad := 4
+	if n.class == elf.ELFCLASS64 {
+		pad = 8
+	}
+
+	var props []ElfProperty
+	for pos := 0; pos+8 <= len(n.Desc); {
+		typ := n.order.Uint32(n.Desc[pos:])
+		size := int(n.order.Uint32(n.Desc[pos+4:]))
+		data := pos + 8
+		if size < 0 || data+size > len(n.Desc) {
+			return props, fmt.Errorf("corrupt GNU property %#x", typ)
+		}
+		props = append(props, ElfProperty{
+			Type:   typ,
+			Data:   n.Desc[data : data+size],
+			Offset: n.DescOffset + int64(data),
+		})
+		pos = (data + size + pad - 1) &^ (pad - 1)
+	}
+	return props, nil
+}
+
+// Feature1 returns the feature bits of the x86 or AArch64 FEATURE_1_AND
+// property of a GNU property note for the file's machine.
+func (n *ElfNote) Feature1() (uint32, bool) {
+	props, err := n.Properties()
+	if err != nil {
+		return 0, false
+	}
+	want := uint32(0)
+	switch n.machine {
+	case elf.EM_386, elf.EM_X86_64:
+		want = ElfPropertyX86Feature1And
+	case elf.EM_AARCH64:
+		want = ElfPropertyAArch64Feature1And
+	default:
+		return 0, false
+	}
+	for _, p := range props {
+		if p.Type == want && len(p.Data) >= 4 {
+			return n.order.Uint32(p.Data), true
+		}
+	}
+	return 0, false
+}
+
+// Features decodes the control flow protection features of a GNU property
+// note.
+func (n *ElfNote) Features() ElfFeatures {
+	var f ElfFeatures
+	bits, ok := n.Feature1()
+	if !ok {
+		return f
+	}
+	switch n.machine {
+	case elf.EM_386, elf.EM_X86_64:
+		f.IBT = bits&ElfPropertyX86Feature1IBT != 0
+		f.SHSTK = bits&ElfPropertyX86Feature1SHSTK != 0
+	case elf.EM_AARCH64:
+		f.BTI = bits&ElfPropertyAArch64Feature1BTI != 0
+		f.PAC = bits&ElfPropertyAArch64Feature1PAC != 0
+	}
+	return f
+}
+
+// Stapsdt decodes a SystemTap probe note.
+func (n *ElfNote) Stapsdt() (*ElfStapsdt, bool) {
+	if !n.is("stapsdt", ElfNoteStapsdt) {
+		return nil, false
+	}
+	size := 8
+	if n.class == elf.ELFCLASS32 {
+		size = 4
+	}
+	if len(n.Desc) < 3*size {
+		return nil, false
+	}
+	addr := func(i int) uint64 {
+		if size == 4 {
+			return uint64(n.order.Uint32(n.Desc[i*size:]))
+		}
+		return n.order.Uint64(n.Desc[i*size:])
+	}
+
+	probe := &ElfStapsdt{PC: addr(0), Base: addr(1), Semaphore: addr(2)}
+	strs := bytes.SplitN(n.Desc[3*size:], []byte{0}, 4)
+	if len(strs) < 3 {
+		return nil, false
+	}
+	probe.Provider, probe.Name, probe.Args = string(strs[0]), string(strs[1]), string(strs[2])
+	return probe, true
+}
+
+// Package decodes the JSON package metadata of a .note.package note.
+func (n *ElfNote) Package() (*ElfPackage, error) {
+	if !n.is("FDO", ElfNoteFdoPackaging) {
+		return nil, fmt.Errorf("not a package metadata note")
+	}
+	raw := bytes.TrimRight(n.Desc, "\x00")
+	pkg := &ElfPackage{Raw: json.RawMessage(raw)}
+	if err := json.Unmarshal(raw, pkg); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// GetElfBuildID returns the build id of abfd, empty if it has none.
+func GetElfBuildID(abfd *File) (string, error) {
+	notes, err := GetElfNotes(abfd)
+	if err != nil {
+		return "", err
+	}
+	for i := range notes {
+		if id, ok := notes[i].BuildID(); ok {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// GetElfFeatures returns the control flow protection features the GNU
+// property notes of abfd mark it with.
+func GetElfFeatures(abfd *File) (ElfFeatures, error) {
+	notes, err := GetElfNotes(abfd)
+	if err != nil {
+		return ElfFeatures{}, err
+	}
+	for i := range notes {
+		if notes[i].is("GNU", ElfNoteGnuProperty) {
+			return notes[i].Features(), nil
+		}
+	}
+	return ElfFeatures{}, nil
+}
@@ -0,0 +1,515 @@
+package bfd
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ElfPatchOptions lists the dynamic linking changes PatchElf makes, the way
+// patchelf does. Empty fields leave the file alone. A non-nil Rpath or
+// Runpath sets that entry, adding it if the file has none; RemoveRpath
+// drops both before either is set.
+type ElfPatchOptions struct {
+	Interp        string
+	Soname        string
+	Rpath         []string
+	Runpath       []string
+	RemoveRpath   bool
+	ReplaceNeeded map[string]string
+	AddNeeded     []string
+	RemoveNeeded  []string
+}
+
+func (o *ElfPatchOptions) editsDynamic() bool {
+	return o.Soname != "" || o.Rpath != nil || o.Runpath != nil || o.RemoveRpath ||
+		len(o.ReplaceNeeded) > 0 || len(o.AddNeeded) > 0 || len(o.RemoveNeeded) > 0
+}
+
+// elfStrtab is a string table that only grows, so the strings already in
+// it keep their offsets.
+type elfStrtab struct {
+	buf  []byte
+	size int
+}
+
+// add returns the offset of str, reusing the string or the tail of a
+// longer one when the table already has it.
+func (t *elfStrtab) add(str string) uint64 {
+	s := append([]byte(str), 0)
+	if i := bytes.Index(t.buf, s); i >= 0 {
+		return uint64(i)
+	}
+	off := len(t.buf)
+	t.buf = append(t.buf, s...)
+	return uint64(off)
+}
+
+func (t *elfStrtab) grown() bool { return len(t.buf) > t.size }
+
+// elfPatcher edits the contents of an ELF file in memory. Whatever no
+// longer fits where it was goes in a new loadable segment at the end of the
+// file, along with a copy of the program header table that has room for
+// the new segment.
+type elfPatcher struct {
+	*elfImage
+	buf     []byte
+	strtab  *elfStrtab
+	dyns    []ElfDyn
+	renamed map[string]uint64
+}
+
+// PatchElf returns the contents of abfd with the program interpreter and
+// dynamic section changed as opts says.
+func PatchElf(abfd *File, opts *ElfPatchOptions) ([]byte, error) {
+	m, err := newElfImage(abfd)
+	if err != nil {
+		return nil, err
+	}
+	if m.hdr.Type != elf.ET_EXEC && m.hdr.Type != elf.ET_DYN {
+		return nil, fmt.Errorf("%s: not an executable or shared object", abfd.Filename())
+	}
+	buf := make([]byte, GetSize(abfd))
+	if _, err := abfd.ReadAt(buf, 0); err != nil {
+		return nil, err
+	}
+
+	p := &elfPatcher{elfImage: m, buf: buf, renamed: make(map[string]uint64)}
+	var interp []byte
+	if opts.Interp != "" {
+		if interp, err = p.setInterp(opts.Interp); err != nil {
+			return nil, err
+		}
+	}
+
+	var moveDynamic bool
+	if opts.editsDynamic() {
+		if err := p.editDynamic(opts); err != nil {
+			return nil, err
+		}
+		off, size := m.dynamicRange()
+		if uint64(len(p.dyns)+1)*p.dynSize() > size {
+			moveDynamic = true
+		} else {
+			copy(p.buf[off:off+int64(size)], p.encodeDynamic(int(size)))
+		}
+		if err := p.fixVerneed(); err != nil {
+			return nil, err
+		}
+	}
+
+	if interp == nil && !moveDynamic && (p.strtab == nil || !p.strtab.grown()) {
+		return p.buf, nil
+	}
+	if err := p.addSegment(interp, moveDynamic); err != nil {
+		return nil, err
+	}
+	return p.buf, nil
+}
+
+// PatchElfFile writes iname patched as opts says to oname, which may be
+// iname, keeping its permissions.
+func PatchElfFile(iname, oname string, opts *ElfPatchOptions) error {
+	abfd, err := Openr(iname, "")
+	if err != nil {
+		return err
+	}
+	defer Close(abfd)
+
+	if err := CheckFormat(abfd, Object); err != nil {
+		return fmt.Errorf("%s: %v", iname, err)
+	}
+	buf, err := PatchElf(abfd, opts)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(iname)
+	if err != nil {
+		return err
+	}
+	return replaceFile(oname, buf, fi.Mode().Perm())
+}
+
+func (p *elfPatcher) phdr(typ elf.ProgType) *ElfProgramHeader {
+	for i := range p.phdrs {
+		if p.phdrs[i].Type == typ {
+			return &p.phdrs[i]
+		}
+	}
+	return nil
+}
+
+func (p *elfPatcher) sectionAt(typ elf.SectionType, off uint64) *ElfSectionHeader {
+	for _, s := range p.sections {
+		if s.Type == typ && s.Offset == off {
+			return s
+		}
+	}
+	return nil
+}
+
+// setInterp writes the new interpreter over the old one if it fits,
+// otherwise it returns the string to put in the new segment.
+func (p *elfPatcher) setInterp(path string) ([]byte, error) {
+	ph := p.phdr(elf.PT_INTERP)
+	if ph == nil {
+		return nil, fmt.Errorf("%s: no program interpreter to replace", p.abfd.Filename())
+	}
+	interp := append([]byte(path), 0)
+	if uint64(len(interp)) > ph.Filesz {
+		return interp, nil
+	}
+	// the kernel wants the last byte of the segment to be the terminator,
+	// so pad it out rather than shrink it
+	region := p.buf[ph.Offset : ph.Offset+ph.Filesz]
+	for i := range region {
+		region[i] = 0
+	}
+	copy(region, interp)
+	return nil, nil
+}
+
+func (p *elfPatcher) editDynamic(opts *ElfPatchOptions) error {
+	dyns, err := p.dynamic()
+	if err != nil {
+		return err
+	}
+	if dyns == nil {
+		return fmt.Errorf("%s: no dynamic section", p.abfd.Filename())
+	}
+	old, err := p.dynstr(dyns)
+	if err != nil {
+		return err
+	}
+	p.strtab = &elfStrtab{buf: append([]byte(nil), old...), size: len(old)}
+	str := func(d ElfDyn) string { return cstring(p.strtab.buf, uint32(d.Val)) }
+
+	remove := make(map[string]bool)
+	for _, name := range opts.RemoveNeeded {
+		remove[name] = true
+	}
+	if err := p.checkRemovable(remove); err != nil {
+		return err
+	}
+
+	var out []ElfDyn
+	needed := make(map[string]bool)
+	lastNeeded := -1
+	var hasSoname, hasRpath, hasRunpath bool
+	for _, d := range dyns {
+		switch d.Tag {
+		case elf.DT_NEEDED:
+			name := str(d)
+			if remove[name] {
+				continue
+			}
+			if to, ok := opts.ReplaceNeeded[name]; ok && to != name {
+				d.Val = p.strtab.add(to)
+				p.renamed[name] = d.Val
+				name = to
+			}
+			needed[name] = true
+			lastNeeded = len(out)
+		case elf.DT_SONAME:
+			if opts.Soname != "" {
+				d.Val = p.strtab.add(opts.Soname)
+			}
+			hasSoname = true
+		case elf.DT_RPATH, elf.DT_RUNPATH:
+			if opts.RemoveRpath {
+				continue
+			}
+			if d.Tag == elf.DT_RPATH && opts.Rpath != nil {
+				d.Val = p.strtab.add(strings.Join(opts.Rpath, ":"))
+			}
+			if d.Tag == elf.DT_RUNPATH && opts.Runpath != nil {
+				d.Val = p.strtab.add(strings.Join(opts.Runpath, ":"))
+			}
+			hasRpath = hasRpath || d.Tag == elf.DT_RPATH
+			hasRunpath = hasRunpath || d.Tag == elf.DT_RUNPATH
+		}
+		out = append(out, d)
+	}
+
+	// new libraries go after the ones already needed, keeping the search
+	// order of those
+	var add []ElfDyn
+	for _, name := range opts.AddNeeded {
+		if !needed[name] {
+			needed[name] = true
+			add = append(add, ElfDyn{Tag: elf.DT_NEEDED, Val: p.strtab.add(name)})
+		}
+	}
+	at := lastNeeded + 1
+	out = append(out[:at], append(add, out[at:]...)...)
+
+	if opts.Soname != "" && !hasSoname {
+		out = append(out, ElfDyn{Tag: elf.DT_SONAME, Val: p.strtab.add(opts.Soname)})
+	}
+	if opts.Rpath != nil && !hasRpath {
+		out = append(out, ElfDyn{Tag: elf.DT_RPATH, Val: p.strtab.add(strings.Join(opts.Rpath, ":"))})
+	}
+	if opts.Runpath != nil && !hasRunpath {
+		out = append(out, ElfDyn{Tag: elf.DT_RUNPATH, Val: p.strtab.add(strings.Join(opts.Runpath, ":"))})
+	}
+	p.dyns = out
+	return nil
+}
+
+// checkRemovable refuses to drop a library symbols are bound to by
+// version, since the dynamic linker insists on finding every library named
+// in the version requirements.
+func (p *elfPatcher) checkRemovable(remove map[string]bool) error {
+	s := p.section(elf.SHT_GNU_VERNEED)
+	if s == nil || len(remove) == 0 {
+		return nil
+	}
+	needs, err := p.verneeds(s)
+	if err != nil {
+		return err
+	}
+	for _, n := range needs {
+		if remove[n.File] {
+			return fmt.Errorf("%s: symbols are bound to versions of %s", p.abfd.Filename(), n.File)
+		}
+	}
+	return nil
+}
+
+// fixVerneed points the version requirements of renamed libraries at their
+// new names.
+func (p *elfPatcher) fixVerneed() error {
+	s := p.section(elf.SHT_GNU_VERNEED)
+	if s == nil || len(p.renamed) == 0 {
+		return nil
+	}
+	off := s.Offset
+	for i := uint32(0); i < s.Info && off+16 <= s.Offset+s.Size; i++ {
+		vn := p.buf[off:]
+		name := cstring(p.strtab.buf, p.order.Uint32(vn[4:]))
+		if to, ok := p.renamed[name]; ok {
+			p.order.PutUint32(vn[4:], uint32(to))
+		}
+		next := p.order.Uint32(vn[12:])
+		if next == 0 {
+			break
+		}
+		off += uint64(next)
+	}
+	return nil
+}
+
+func (p *elfPatcher) wordSize() uint64 {
+	if p.hdr.Class == elf.ELFCLASS32 {
+		return 4
+	}
+	return 8
+}
+
+func (p *elfPatcher) dynSize() uint64 { return 2 * p.wordSize() }
+
+func (p *elfPatcher) putWord(b []byte, v uint64) {
+	if p.hdr.Class == elf.ELFCLASS32 {
+		p.order.PutUint32(b, uint32(v))
+	} else {
+		p.order.PutUint64(b, v)
+	}
+}
+
+// encodeDynamic lays out the dynamic entries in size bytes; the rest is
+// DT_NULL.
+func (p *elfPatcher) encodeDynamic(size int) []byte {
+	b := make([]byte, size)
+	w := p.wordSize()
+	for i, d := range p.dyns {
+		off := uint64(i) * 2 * w
+		p.putWord(b[off:], uint64(d.Tag))
+		p.putWord(b[off+w:], d.Val)
+	}
+	return b
+}
+
+func (p *elfPatcher) setDyn(tag elf.DynTag, val uint64) error {
+	for i := range p.dyns {
+		if p.dyns[i].Tag == tag {
+			p.dyns[i].Val = val
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: no %v in the dynamic section", p.abfd.Filename(), tag)
+}
+
+// setSection moves section header s to off and addr in the file.
+func (p *elfPatcher) setSection(s *ElfSectionHeader, addr, off, size uint64) {
+	b := p.buf[p.hdr.Shoff+uint64(s.Index)*uint64(p.hdr.Shentsize):]
+	if p.hdr.Class == elf.ELFCLASS32 {
+		p.order.PutUint32(b[12:], uint32(addr))
+		p.order.PutUint32(b[16:], uint32(off))
+		p.order.PutUint32(b[20:], uint32(size))
+	} else {
+		p.order.PutUint64(b[16:], addr)
+		p.order.PutUint64(b[24:], off)
+		p.order.PutUint64(b[32:], size)
+	}
+}
+
+func (p *elfPatcher) encodePhdrs(phdrs []ElfProgramHeader) []byte {
+	var b bytes.Buffer
+	for _, ph := range phdrs {
+		start := b.Len()
+		if p.hdr.Class == elf.ELFCLASS32 {
+			binary.Write(&b, p.order, elf.Prog32{
+				Type: uint32(ph.Type), Flags: uint32(ph.Flags), Off: uint32(ph.Offset),
+				Vaddr: uint32(ph.Vaddr), Paddr: uint32(ph.Paddr),
+				Filesz: uint32(ph.Filesz), Memsz: uint32(ph.Memsz), Align: uint32(ph.Align),
+			})
+		} else {
+			binary.Write(&b, p.order, elf.Prog64{
+				Type: uint32(ph.Type), Flags: uint32(ph.Flags), Off: ph.Offset,
+				Vaddr: ph.Vaddr, Paddr: ph.Paddr,
+				Filesz: ph.Filesz, Memsz: ph.Memsz, Align: ph.Align,
+			})
+		}
+		b.Write(make([]byte, start+int(p.hdr.Phentsize)-b.Len()))
+	}
+	return b.Bytes()
+}
+
+// addSegment appends a loadable segment holding the program header table
+// and whatever outgrew its old place.
+//
+// The segment goes past both the end of the file and the end of the address
+// space in use, at an address that is as far from its file offset as the
+// first segment's is. Older kernels compute the address of the program
+// headers from the first segment alone, and this keeps them right.
+func (p *elfPatcher) addSegment(interp []byte, moveDynamic bool) error {
+	var first *ElfProgramHeader
+	last, end := -1, uint64(0)
+	for i := range p.phdrs {
+		ph := &p.phdrs[i]
+		if ph.Type != elf.PT_LOAD {
+			continue
+		}
+		if first == nil {
+			first = ph
+		}
+		if ph.Vaddr+ph.Memsz > end {
+			end = ph.Vaddr + ph.Memsz
+		}
+		last = i
+	}
+	if first == nil {
+		return fmt.Errorf("%s: no loadable segments", p.abfd.Filename())
+	}
+	if p.hdr.Phnum+1 >= 0xffff {
+		return fmt.Errorf("%s: too many program headers", p.abfd.Filename())
+	}
+
+	page := first.Align
+	if page < 0x1000 {
+		page = 0x1000
+	}
+	roundup := func(n, a uint64) uint64 { return (n + a - 1) &^ (a - 1) }
+	delta := first.Vaddr - first.Offset
+	off := roundup(uint64(len(p.buf)), page)
+	if o := roundup(end, page) - delta; o > off {
+		off = o
+	}
+	vaddr := off + delta
+
+	// lay out the segment: program headers, string table, dynamic section
+	// and interpreter, each 8 byte aligned
+	var seg []byte
+	place := func(size uint64) uint64 {
+		pos := roundup(uint64(len(seg)), 8)
+		seg = append(seg, make([]byte, pos+size-uint64(len(seg)))...)
+		return pos
+	}
+	phnum := p.hdr.Phnum + 1
+	phdrPos := place(uint64(phnum) * uint64(p.hdr.Phentsize))
+
+	flags := elf.PF_R
+	if p.strtab != nil && p.strtab.grown() {
+		oldAddr := uint64(0)
+		for _, d := range p.dyns {
+			if d.Tag == elf.DT_STRTAB {
+				oldAddr = d.Val
+			}
+		}
+		pos := place(uint64(len(p.strtab.buf)))
+		copy(seg[pos:], p.strtab.buf)
+		if err := p.setDyn(elf.DT_STRTAB, vaddr+pos); err != nil {
+			return err
+		}
+		if err := p.setDyn(elf.DT_STRSZ, uint64(len(p.strtab.buf))); err != nil {
+			return err
+		}
+		if oldOff, ok := p.offset(oldAddr); ok {
+			if s := p.sectionAt(elf.SHT_STRTAB, uint64(oldOff)); s != nil {
+				p.setSection(s, vaddr+pos, off+pos, uint64(len(p.strtab.buf)))
+			}
+		}
+		if !moveDynamic {
+			dynOff, size := p.dynamicRange()
+			copy(p.buf[dynOff:dynOff+int64(size)], p.encodeDynamic(int(size)))
+		}
+	}
+
+	if moveDynamic {
+		// the dynamic linker writes to the dynamic section
+		flags |= elf.PF_W
+		size := uint64(len(p.dyns)+1) * p.dynSize()
+		pos := place(size)
+		copy(seg[pos:], p.encodeDynamic(int(size)))
+		if ph := p.phdr(elf.PT_DYNAMIC); ph != nil {
+			ph.Offset, ph.Vaddr, ph.Paddr = off+pos, vaddr+pos, vaddr+pos
+			ph.Filesz, ph.Memsz = size, size
+		}
+		if s := p.section(elf.SHT_DYNAMIC); s != nil {
+			p.setSection(s, vaddr+pos, off+pos, size)
+		}
+	}
+
+	if interp != nil {
+		pos := place(uint64(len(interp)))
+		copy(seg[pos:], interp)
+		ph := p.phdr(elf.PT_INTERP)
+		if s := p.sectionAt(elf.SHT_PROGBITS, ph.Offset); s != nil && s.Name == ".interp" {
+			p.setSection(s, vaddr+pos, off+pos, uint64(len(interp)))
+		}
+		ph.Offset, ph.Vaddr, ph.Paddr = off+pos, vaddr+pos, vaddr+pos
+		ph.Filesz, ph.Memsz = uint64(len(interp)), uint64(len(interp))
+	}
+
+	size := uint64(phnum) * uint64(p.hdr.Phentsize)
+	if ph := p.phdr(elf.PT_PHDR); ph != nil {
+		ph.Offset, ph.Vaddr, ph.Paddr = off+phdrPos, vaddr+phdrPos, vaddr+phdrPos
+		ph.Filesz, ph.Memsz = size, size
+	}
+	load := ElfProgramHeader{
+		Type:   elf.PT_LOAD,
+		Flags:  flags,
+		Offset: off,
+		Vaddr:  vaddr,
+		Paddr:  vaddr,
+		Filesz: uint64(len(seg)),
+		Memsz:  uint64(len(seg)),
+		Align:  page,
+	}
+	// loadable segments have to stay sorted by address
+	phdrs := append(append(append([]ElfProgramHeader(nil), p.phdrs[:last+1]...), load), p.phdrs[last+1:]...)
+	copy(seg[phdrPos:], p.encodePhdrs(phdrs))
+
+	if p.hdr.Class == elf.ELFCLASS32 {
+		p.order.PutUint32(p.buf[28:], uint32(off+phdrPos))
+		p.order.PutUint16(p.buf[44:], uint16(phnum))
+	} else {
+		p.order.PutUint64(p.buf[32:], off+phdrPos)
+		p.order.PutUint16(p.buf[56:], uint16(phnum))
+	}
+	p.buf = append(append(p.buf, make([]byte, off-uint64(len(p.buf)))...), seg...)
+	return nil
+}
@@ -0,0 +1,183 @@
+package bfd
+
+import (
+	"bytes"
+	"debug/elf"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestElfStrtabAdd(t *testing.T) {
+	tab := &elfStrtab{buf: []byte("\x00libfoo.so\x00"), size: 11}
+	if off := tab.add("libfoo.so"); off != 1 {
+		t.Errorf("add(libfoo.so) = %d, want 1", off)
+	}
+	if off := tab.add("foo.so"); off != 4 {
+		t.Errorf("add(foo.so) = %d, want the tail of libfoo.so at 4", off)
+	}
+	if off := tab.add(""); off != 0 {
+		t.Errorf("add(\"\") = %d, want 0", off)
+	}
+	if tab.grown() {
+		t.Fatalf("table grew to %q reusing strings it had", tab.buf)
+	}
+	if off := tab.add("libbar.so"); off != 11 {
+		t.Errorf("add(libbar.so) = %d, want 11", off)
+	}
+	if !tab.grown() || string(tab.buf) != "\x00libfoo.so\x00libbar.so\x00" {
+		t.Errorf("table is %q after adding libbar.so", tab.buf)
+	}
+}
+
+func TestPatchElf(t *testing.T) {
+	runpath := []string{"/opt/a/rather/long/library/directory", "/opt/another/long/library/directory"}
+	interp := "/opt/a/rather/long/library/directory/ld-linux-x86-64.so.2"
+	tests := []struct {
+		name string
+		opts ElfPatchOptions
+	}{
+		// the dynamic section has room for one more entry, so only the
+		// string table moves
+		{"runpath", ElfPatchOptions{Runpath: runpath, AddNeeded: []string{"libm.so.6"}}},
+		{"dynamic", ElfPatchOptions{Runpath: runpath, AddNeeded: []string{"libm.so.6", "libdl.so.2", "librt.so.1", "libpthread.so.0", "libutil.so.1"}}},
+		{"interp", ElfPatchOptions{Interp: interp}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			abfd, err := Openr("testdata/hello-pie", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer Close(abfd)
+			if err := CheckFormat(abfd, Object); err != nil {
+				t.Fatal(err)
+			}
+			buf, err := PatchElf(abfd, &tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ef, err := elf.NewFile(bytes.NewReader(buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkElfSegments(t, ef, buf)
+
+			if tt.opts.Interp != "" {
+				ph := findProg(ef, elf.PT_INTERP)
+				if got := string(buf[ph.Off : ph.Off+ph.Filesz]); got != tt.opts.Interp+"\x00" {
+					t.Errorf("interpreter is %q, want %q", got, tt.opts.Interp)
+				}
+				checkElfSection(t, ef, ".interp", ph.Vaddr, ph.Filesz)
+			}
+			if tt.opts.Runpath != nil {
+				checkElfDynamic(t, ef, &tt.opts)
+			}
+		})
+	}
+}
+
+func findProg(ef *elf.File, typ elf.ProgType) *elf.Prog {
+	for _, p := range ef.Progs {
+		if p.Type == typ {
+			return p
+		}
+	}
+	return nil
+}
+
+// elfFileOffset maps addr to a file offset through the loadable segments.
+func elfFileOffset(ef *elf.File, addr uint64) (uint64, bool) {
+	for _, p := range ef.Progs {
+		if p.Type == elf.PT_LOAD && addr >= p.Vaddr && addr < p.Vaddr+p.Filesz {
+			return addr - p.Vaddr + p.Off, true
+		}
+	}
+	return 0, false
+}
+
+// checkElfSegments checks that the segments are in the file, the loadable
+// ones sorted and apart, and that the program headers are where PT_PHDR and
+// the file header say.
+func checkElfSegments(t *testing.T, ef *elf.File, buf []byte) {
+	t.Helper()
+	var prev *elf.Prog
+	for _, p := range ef.Progs {
+		if p.Off+p.Filesz > uint64(len(buf)) {
+			t.Errorf("%v segment at %#x+%#x is past the end of the file", p.Type, p.Off, p.Filesz)
+		}
+		if p.Type == elf.PT_LOAD && p.Filesz > 0 {
+			if off, ok := elfFileOffset(ef, p.Vaddr); !ok || off != p.Off {
+				t.Errorf("%v segment at %#x does not map to its offset %#x", p.Type, p.Vaddr, p.Off)
+			}
+		}
+		if p.Type != elf.PT_LOAD {
+			continue
+		}
+		if prev != nil && prev.Vaddr+prev.Memsz > p.Vaddr {
+			t.Errorf("loadable segment at %#x comes after the one at %#x", p.Vaddr, prev.Vaddr)
+		}
+		prev = p
+	}
+
+	phoff := ef.ByteOrder.Uint64(buf[32:])
+	phentsize := uint64(ef.ByteOrder.Uint16(buf[54:]))
+	ph := findProg(ef, elf.PT_PHDR)
+	if ph == nil {
+		t.Fatal("no PT_PHDR")
+	}
+	if ph.Off != phoff || ph.Filesz != uint64(len(ef.Progs))*phentsize {
+		t.Errorf("PT_PHDR covers %#x+%#x, want %#x+%#x", ph.Off, ph.Filesz, phoff, uint64(len(ef.Progs))*phentsize)
+	}
+	if off, ok := elfFileOffset(ef, ph.Vaddr); !ok || off != ph.Off {
+		t.Errorf("PT_PHDR at %#x is not loaded from %#x", ph.Vaddr, ph.Off)
+	}
+}
+
+func checkElfSection(t *testing.T, ef *elf.File, name string, addr, size uint64) {
+	t.Helper()
+	s := ef.Section(name)
+	if s == nil {
+		t.Fatalf("no %s section", name)
+	}
+	if s.Addr != addr || s.Size != size {
+		t.Errorf("%s section is at %#x+%#x, want %#x+%#x", name, s.Addr, s.Size, addr, size)
+	}
+	if off, ok := elfFileOffset(ef, addr); !ok || off != s.Offset {
+		t.Errorf("%s section at %#x is not loaded from %#x", name, addr, s.Offset)
+	}
+}
+
+// checkElfDynamic checks that the dynamic section and its string table are
+// where the segments, section headers and dynamic entries say, and that
+// they hold what opts asked for.
+func checkElfDynamic(t *testing.T, ef *elf.File, opts *ElfPatchOptions) {
+	t.Helper()
+	ph := findProg(ef, elf.PT_DYNAMIC)
+	checkElfSection(t, ef, ".dynamic", ph.Vaddr, ph.Filesz)
+
+	strtab, err := ef.DynValue(elf.DT_STRTAB)
+	if err != nil || len(strtab) != 1 {
+		t.Fatalf("DT_STRTAB is %v: %v", strtab, err)
+	}
+	strsz, err := ef.DynValue(elf.DT_STRSZ)
+	if err != nil || len(strsz) != 1 {
+		t.Fatalf("DT_STRSZ is %v: %v", strsz, err)
+	}
+	checkElfSection(t, ef, ".dynstr", strtab[0], strsz[0])
+
+	runpath, err := ef.DynString(elf.DT_RUNPATH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{strings.Join(opts.Runpath, ":")}; !reflect.DeepEqual(runpath, want) {
+		t.Errorf("DT_RUNPATH is %q, want %q", runpath, want)
+	}
+	libs, err := ef.ImportedLibraries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]string{"libc.so.6"}, opts.AddNeeded...); !reflect.DeepEqual(libs, want) {
+		t.Errorf("DT_NEEDED are %q, want %q", libs, want)
+	}
+}
@@ -0,0 +1,136 @@
+package bfd
+
+import (
+	"debug/elf"
+	"sort"
+	"strings"
+)
+
+// ElfRelro is how much of the relocated data is made read-only after
+// loading.
+type ElfRelro int
+
+const (
+	RelroNone ElfRelro = iota
+	RelroPartial
+	RelroFull
+)
+
+func (r ElfRelro) String() string {
+	switch r {
+	case RelroPartial:
+		return "Partial RELRO"
+	case RelroFull:
+		return "Full RELRO"
+	}
+	return "No RELRO"
+}
+
+func (r ElfRelro) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// ElfHardening is the exploit mitigations an executable or shared object
+// was built with. Fortified lists the fortified _chk functions it calls and
+// WXSegments the indexes of the loadable segments that are both writable
+// and executable.
+type ElfHardening struct {
+	PIE          bool
+	SharedObject bool
+	NX           bool
+	Relro        ElfRelro
+	Canary       bool
+	Fortified    []string
+	Features     ElfFeatures
+	Rpath        []string
+	Runpath      []string
+	WXSegments   []int
+}
+
+// GetElfHardening checks the exploit mitigations of abfd, the way checksec
+// does.
+func GetElfHardening(abfd *File) (*ElfHardening, error) {
+	m, err := newElfImage(abfd)
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := GetElfDynamic(abfd)
+	if err != nil {
+		return nil, err
+	}
+	features, err := GetElfFeatures(abfd)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &ElfHardening{Features: features}
+	// without PT_GNU_STACK the kernel falls back to an executable stack, so
+	// NX is only set by one that asks for a non-executable stack
+	var interp, relro bool
+	for i, p := range m.phdrs {
+		switch p.Type {
+		case elf.PT_INTERP:
+			interp = true
+		case elf.PT_GNU_RELRO:
+			relro = true
+		case elf.PT_GNU_STACK:
+			h.NX = p.Flags&elf.PF_X == 0
+		case elf.PT_LOAD:
+			if p.Flags&(elf.PF_W|elf.PF_X) == elf.PF_W|elf.PF_X {
+				h.WXSegments = append(h.WXSegments, i)
+			}
+		}
+	}
+
+	var bindNow, pieFlag bool
+	if dyn != nil {
+		bindNow = dyn.Flags&elf.DF_BIND_NOW != 0 || dyn.Flags1&elf.DF_1_NOW != 0
+		pieFlag = dyn.Flags1&elf.DF_1_PIE != 0
+		for _, d := range dyn.Entries {
+			if d.Tag == elf.DT_BIND_NOW {
+				bindNow = true
+			}
+		}
+		h.Rpath, h.Runpath = dyn.Rpath, dyn.Runpath
+	}
+	if m.hdr.Type == elf.ET_DYN {
+		h.PIE = interp || pieFlag
+		h.SharedObject = !h.PIE
+	}
+	switch {
+	case relro && bindNow:
+		h.Relro = RelroFull
+	case relro:
+		h.Relro = RelroPartial
+	}
+
+	fortified := make(map[string]bool)
+	for _, typ := range []elf.SectionType{elf.SHT_DYNSYM, elf.SHT_SYMTAB} {
+		s := m.section(typ)
+		if s == nil {
+			continue
+		}
+		syms, err := m.symbols(s)
+		if err != nil {
+			return nil, err
+		}
+		for _, sym := range syms {
+			// linked files keep the version in static symbol names
+			name := sym.Name
+			if i := strings.IndexByte(name, '@'); i >= 0 {
+				name = name[:i]
+			}
+			switch {
+			case name == "__stack_chk_fail" || name == "__stack_chk_guard" || name == "__intel_security_cookie":
+				h.Canary = true
+			case strings.HasPrefix(name, "__") && strings.HasSuffix(name, "_chk"):
+				fortified[name] = true
+			}
+		}
+	}
+	for name := range fortified {
+		h.Fortified = append(h.Fortified, name)
+	}
+	sort.Strings(h.Fortified)
+	return h, nil
+}
@@ -0,0 +1,126 @@
+package bfd
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+)
+
+// ElfSymbol is a symbol as the ELF symbol table has it, with the details
+// the generic symbol drops. Section is the raw section index, which can be
+// one of the reserved SHN_UNDEF, SHN_ABS and SHN_COMMON.
+type ElfSymbol struct {
+	Name       string
+	Value      uint64
+	Size       uint64
+	Type       elf.SymType
+	Bind       elf.SymBind
+	Visibility elf.SymVis
+	Section    elf.SectionIndex
+}
+
+// IsExported reports whether the symbol is visible outside the object, by
+// binding and visibility.
+func (s *ElfSymbol) IsExported() bool {
+	if s.Section == elf.SHN_UNDEF || s.Bind == elf.STB_LOCAL {
+		return false
+	}
+	return s.Visibility == elf.STV_DEFAULT || s.Visibility == elf.STV_PROTECTED
+}
+
+// symbols reads the symbol table section s, including the null symbol at
+// index 0.
+func (m *elfImage) symbols(s *ElfSectionHeader) ([]ElfSymbol, error) {
+	buf, err := m.readAt(int64(s.Offset), s.Size)
+	if err != nil {
+		return nil, err
+	}
+	var strtab []byte
+	if int(s.Link) < len(m.sections) {
+		str := m.sections[s.Link]
+		if strtab, err = m.readAt(int64(str.Offset), str.Size); err != nil {
+			return nil, err
+		}
+	}
+	xindex, err := m.symtabShndx(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var syms []ElfSymbol
+	r := bytes.NewReader(buf)
+	for r.Len() > 0 {
+		var name uint32
+		var info, other uint8
+		var sym ElfSymbol
+		if m.hdr.Class == elf.ELFCLASS32 {
+			var e elf.Sym32
+			if err := binary.Read(r, m.order, &e); err != nil {
+				break
+			}
+			name, info, other = e.Name, e.Info, e.Other
+			sym.Section, sym.Value, sym.Size = elf.SectionIndex(e.Shndx), uint64(e.Value), uint64(e.Size)
+		} else {
+			var e elf.Sym64
+			if err := binary.Read(r, m.order, &e); err != nil {
+				break
+			}
+			name, info, other = e.Name, e.Info, e.Other
+			sym.Section, sym.Value, sym.Size = elf.SectionIndex(e.Shndx), e.Value, e.Size
+		}
+		sym.Name = cstring(strtab, name)
+		sym.Type, sym.Bind = elf.ST_TYPE(info), elf.ST_BIND(info)
+		sym.Visibility = elf.ST_VISIBILITY(other)
+		if sym.Section == elf.SHN_XINDEX && 4*len(syms)+4 <= len(xindex) {
+			sym.Section = elf.SectionIndex(m.order.Uint32(xindex[4*len(syms):]))
+		}
+		syms = append(syms, sym)
+	}
+	return syms, nil
+}
+
+// symtabShndx reads the extended section indexes for symtab, if the file
+// has so many sections that it needs them.
+func (m *elfImage) symtabShndx(symtab *ElfSectionHeader) ([]byte, error) {
+	for _, s := range m.sections {
+		if s.Type == elf.SHT_SYMTAB_SHNDX && int(s.Link) == symtab.Index {
+			return m.readAt(int64(s.Offset), s.Size)
+		}
+	}
+	return nil, nil
+}
+
+// GetElfSymbols reads the symbol table, or the dynamic symbol table if
+// dynamic is set. The null symbol at index 0 is left out, so the list lines
+// up with what CanonicalizeSymtab and CanonicalizeDynamicSymtab return for
+// the same file.
+func GetElfSymbols(abfd *File, dynamic bool) ([]ElfSymbol, error) {
+	m, err := newElfImage(abfd)
+	if err != nil {
+		return nil, err
+	}
+	typ := elf.SHT_SYMTAB
+	if dynamic {
+		typ = elf.SHT_DYNSYM
+	}
+	s := m.section(typ)
+	if s == nil {
+		return nil, nil
+	}
+	if s.Entsize != 0 && s.Entsize != m.symbolSize() {
+		return nil, fmt.Errorf("%s: %s: bad symbol entry size %d", abfd.Filename(), s.Name, s.Entsize)
+	}
+	syms, err := m.symbols(s)
+	if err != nil || len(syms) == 0 {
+		return nil, err
+	}
+	return syms[1:], nil
+}
+
+func (m *elfImage) symbolSize() uint64 {
+	if m.hdr.Class == elf.ELFCLASS32 {
+		return elf.Sym32Size
+	}
+	return elf.Sym64Size
+}
@@ -0,0 +1,228 @@
+package bfd
+
+import (
+	"debug/elf"
+	"fmt"
+)
+
+const (
+	// ElfVersymHidden is set in a version index for a symbol that only
+	// binds by explicit version, printed as sym@VERSION instead of
+	// sym@@VERSION.
+	ElfVersymHidden = 0x8000
+
+	// ElfVerFlagBase marks the version definition naming the file itself.
+	ElfVerFlagBase = 0x1
+	// ElfVerFlagWeak marks a weak version reference.
+	ElfVerFlagWeak = 0x2
+)
+
+// ElfVerdef is a version a shared object defines. Parents are the versions
+// this one inherits from.
+type ElfVerdef struct {
+	Index   uint16
+	Flags   uint16
+	Hash    uint32
+	Name    string
+	Parents []string
+}
+
+// ElfVernaux is a version required from a library.
+type ElfVernaux struct {
+	Index uint16
+	Flags uint16
+	Hash  uint32
+	Name  string
+}
+
+// ElfVerneed lists the versions required from one library.
+type ElfVerneed struct {
+	File     string
+	Versions []ElfVernaux
+}
+
+// ElfSymbolVersion is the version of a dynamic symbol. Library is set for
+// versions required from another object and empty for the ones this file
+// defines.
+type ElfSymbolVersion struct {
+	Name    string
+	Index   uint16
+	Version string
+	Library string
+	Hidden  bool
+}
+
+// ElfVersions holds the GNU symbol versioning of a file. Symbols parallels
+// the dynamic symbol table, including the null symbol at index 0.
+type ElfVersions struct {
+	Defs    []ElfVerdef
+	Needs   []ElfVerneed
+	Symbols []ElfSymbolVersion
+}
+
+// sectionStrings reads s along with the string table it links to.
+func (m *elfImage) sectionStrings(s *ElfSectionHeader) (buf, strtab []byte, err error) {
+	if buf, err = m.readAt(int64(s.Offset), s.Size); err != nil {
+		return
+	}
+	if int(s.Link) >= len(m.sections) {
+		return nil, nil, fmt.Errorf("%s: %s: bad string table index %d", m.abfd.Filename(), s.Name, s.Link)
+	}
+	str := m.sections[s.Link]
+	strtab, err = m.readAt(int64(str.Offset), str.Size)
+	return
+}
+
+func (m *elfImage) verdefs(s *ElfSectionHeader) ([]ElfVerdef, error) {
+	buf, strtab, err := m.sectionStrings(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []ElfVerdef
+	for off, i := uint32(0), uint32(0); i < s.Info && uint64(off)+20 <= uint64(len(buf)); i++ {
+		d := buf[off:]
+		def := ElfVerdef{
+			Flags: m.order.Uint16(d[2:]),
+			Index: m.order.Uint16(d[4:]),
+			Hash:  m.order.Uint32(d[8:]),
+		}
+		count := m.order.Uint16(d[6:])
+		aux := off + m.order.Uint32(d[12:])
+		for j := uint16(0); j < count && uint64(aux)+8 <= uint64(len(buf)); j++ {
+			name := cstring(strtab, m.order.Uint32(buf[aux:]))
+			if j == 0 {
+				def.Name = name
+			} else {
+				def.Parents = append(def.Parents, name)
+			}
+			next := m.order.Uint32(buf[aux+4:])
+			if next == 0 {
+				break
+			}
+			aux += next
+		}
+		defs = append(defs, def)
+
+		next := m.order.Uint32(d[16:])
+		if next == 0 {
+			break
+		}
+		off += next
+	}
+	return defs, nil
+}
+
+func (m *elfImage) verneeds(s *ElfSectionHeader) ([]ElfVerneed, error) {
+	buf, strtab, err := m.sectionStrings(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var needs []ElfVerneed
+	for off, i := uint32(0), uint32(0); i < s.Info && uint64(off)+16 <= uint64(len(buf)); i++ {
+		d := buf[off:]
+		need := ElfVerneed{File: cstring(strtab, m.order.Uint32(d[4:]))}
+		count := m.order.Uint16(d[2:])
+		aux := off + m.order.Uint32(d[8:])
+		for j := uint16(0); j < count && uint64(aux)+16 <= uint64(len(buf)); j++ {
+			a := buf[aux:]
+			need.Versions = append(need.Versions, ElfVernaux{
+				Hash:  m.order.Uint32(a[0:]),
+				Flags: m.order.Uint16(a[4:]),
+				Index: m.order.Uint16(a[6:]),
+				Name:  cstring(strtab, m.order.Uint32(a[8:])),
+			})
+			next := m.order.Uint32(a[12:])
+			if next == 0 {
+				break
+			}
+			aux += next
+		}
+		needs = append(needs, need)
+
+		next := m.order.Uint32(d[12:])
+		if next == 0 {
+			break
+		}
+		off += next
+	}
+	return needs, nil
+}
+
+// GetElfVersions reads the version definitions, the version requirements
+// and the version of each dynamic symbol, returning nil if the file is not
+// versioned.
+func GetElfVersions(abfd *File) (*ElfVersions, error) {
+	m, err := newElfImage(abfd)
+	if err != nil {
+		return nil, err
+	}
+
+	ver := &ElfVersions{}
+	if s := m.section(elf.SHT_GNU_VERDEF); s != nil {
+		if ver.Defs, err = m.verdefs(s); err != nil {
+			return nil, err
+		}
+	}
+	if s := m.section(elf.SHT_GNU_VERNEED); s != nil {
+		if ver.Needs, err = m.verneeds(s); err != nil {
+			return nil, err
+		}
+	}
+	versym := m.section(elf.SHT_GNU_VERSYM)
+	dynsym := m.section(elf.SHT_DYNSYM)
+	if versym == nil || dynsym == nil {
+		if ver.Defs == nil && ver.Needs == nil {
+			return nil, nil
+		}
+		return ver, nil
+	}
+
+	syms, err := m.symbols(dynsym)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := m.readAt(int64(versym.Offset), versym.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	ver.Symbols = make([]ElfSymbolVersion, len(syms))
+	for i, sym := range syms {
+		sv := &ver.Symbols[i]
+		sv.Name = sym.Name
+		if 2*i+2 > len(buf) {
+			continue
+		}
+		index := m.order.Uint16(buf[2*i:])
+		sv.Hidden = index&ElfVersymHidden != 0
+		sv.Index = index &^ ElfVersymHidden
+		sv.Version, sv.Library = ver.lookup(sv.Index)
+	}
+	return ver, nil
+}
+
+// lookup names version index, returning the library too for a required
+// version. Local and global indexes and the base definition have no name.
+func (v *ElfVersions) lookup(index uint16) (version, library string) {
+	if index < 2 {
+		return "", ""
+	}
+	for _, d := range v.Defs {
+		if d.Index == index {
+			if d.Flags&ElfVerFlagBase != 0 {
+				return "", ""
+			}
+			return d.Name, ""
+		}
+	}
+	for _, n := range v.Needs {
+		for _, a := range n.Versions {
+			if a.Index == index {
+				return a.Name, n.File
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,251 @@
+package bfd
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FSOptions controls what NewFS shows of a bfd besides archive members.
+type FSOptions struct {
+	// Sections turns every object into a directory holding its sections
+	Sections bool
+}
+
+// FS is a read-only file system view of a bfd. The root holds a single
+// entry named after the file; archives are directories of their members and,
+// with FSOptions.Sections, objects are directories of their sections, so
+// paths look like libfoo.a/bar.o/.text. Members and sections sharing a name
+// with an earlier one get ;2, ;3 and so on appended, as in bar.o;2.
+// Everything else is a regular file with the raw bytes. The view is only
+// valid while the bfd is open.
+type FS struct {
+	mu   sync.Mutex
+	root *fsNode
+}
+
+type fsNode struct {
+	info     fileInfo
+	abfd     *File
+	section  *Section
+	children []*fsNode
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	sys     interface{}
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return fi.sys }
+
+func (fi *fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+func (fi *fileInfo) Type() fs.FileMode          { return fi.mode.Type() }
+
+// NewFS builds the file system view of abfd.
+func NewFS(abfd *File, opts *FSOptions) (*FS, error) {
+	if opts == nil {
+		opts = &FSOptions{}
+	}
+
+	info := fileInfo{
+		name:    filepath.Base(abfd.Filename()),
+		size:    GetSize(abfd),
+		mode:    0444,
+		modTime: time.Unix(GetMtime(abfd), 0),
+	}
+	top, err := newFSNode(abfd, info, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &fsNode{
+		info:     fileInfo{name: ".", mode: fs.ModeDir | 0555, modTime: info.modTime},
+		children: []*fsNode{top},
+	}
+	return &FS{root: root}, nil
+}
+
+func newFSNode(abfd *File, info fileInfo, opts *FSOptions) (*fsNode, error) {
+	n := &fsNode{info: info, abfd: abfd}
+
+	if CheckFormat(abfd, Archive) == nil {
+		n.info.mode = fs.ModeDir | 0555
+		n.info.size = 0
+
+		var member *File
+		var err error
+		for {
+			member, err = OpenrNextArchivedFile(abfd, member)
+			if err == ErrNoMoreArchivedFiles {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			info := fileInfo{
+				name:    filepath.Base(member.Filename()),
+				size:    GetSize(member),
+				mode:    0444,
+				modTime: n.info.modTime,
+			}
+			if st, err := StatArchElt(member); err == nil {
+				info.size = st.Size
+				info.mode = st.Mode
+				info.modTime = st.ModTime
+				info.sys = st
+			}
+			if !validFSName(info.name) {
+				continue
+			}
+			info.name = n.uniqueName(info.name)
+
+			child, err := newFSNode(member, info, opts)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		}
+		return n, nil
+	}
+
+	if opts.Sections && CheckFormat(abfd, Object) == nil {
+		n.info.mode = fs.ModeDir | 0555
+		n.info.size = 0
+
+		for s := abfd.Sections(); s != nil; s = s.Next() {
+			name := s.Name()
+			if !validFSName(name) {
+				continue
+			}
+			name = n.uniqueName(name)
+
+			var size int64
+			if s.Flags()&SEC_HAS_CONTENTS != 0 {
+				size = s.Size()
+			}
+			n.children = append(n.children, &fsNode{
+				info: fileInfo{
+					name:    name,
+					size:    size,
+					mode:    0444,
+					modTime: n.info.modTime,
+					sys:     s,
+				},
+				abfd:    abfd,
+				section: s,
+			})
+		}
+	}
+	return n, nil
+}
+
+func validFSName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+func (n *fsNode) lookup(name string) *fsNode {
+	for _, c := range n.children {
+		if c.info.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// uniqueName returns name, or name;N if N-1 children are already called
+// that, so archives with two foo.o members keep both.
+func (n *fsNode) uniqueName(name string) string {
+	unique := name
+	for i := 2; n.lookup(unique) != nil; i++ {
+		unique = name + ";" + strconv.Itoa(i)
+	}
+	return unique
+}
+
+func (n *fsNode) contents() ([]byte, error) {
+	buf := make([]byte, n.info.size)
+	if n.section != nil {
+		return buf, GetSectionContents(n.abfd, n.section, buf, 0)
+	}
+	_, err := n.abfd.ReadAt(buf, 0)
+	return buf, err
+}
+
+func (f *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	n := f.root
+	if name != "." {
+		for _, elem := range strings.Split(name, "/") {
+			if n = n.lookup(elem); n == nil {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+			}
+		}
+	}
+
+	if n.info.IsDir() {
+		return &fsDir{node: n}, nil
+	}
+
+	// bfd reads go through a shared file position
+	f.mu.Lock()
+	buf, err := n.contents()
+	f.mu.Unlock()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &fsFile{node: n, Reader: bytes.NewReader(buf)}, nil
+}
+
+type fsFile struct {
+	node *fsNode
+	*bytes.Reader
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return &f.node.info, nil }
+func (f *fsFile) Close() error               { return nil }
+
+type fsDir struct {
+	node *fsNode
+	pos  int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return &d.node.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *fsDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	children := d.node.children[d.pos:]
+	if count > 0 && len(children) == 0 {
+		return nil, io.EOF
+	}
+	if count > 0 && count < len(children) {
+		children = children[:count]
+	}
+	d.pos += len(children)
+
+	entries := make([]fs.DirEntry, len(children))
+	for i, c := range children {
+		entries[i] = &c.info
+	}
+	return entries, nil
+}
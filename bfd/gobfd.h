@@ -2,6 +2,7 @@ bfd_vma getStartAddress(bfd *abfd);
 void *readSymbolTable(bfd *abfd, bfd_boolean dynamic, unsigned int *size, long *count);
 int getFileFlags(bfd *abfd);
 void setFileFlags(bfd *abfd, int flags);
+void setBfdUseFlags(bfd *abfd, int flags);
 long getSymtabUpperBound(bfd *abfd);
 long getDynamicSymtabUpperBound(bfd *abfd);
 long canonicalizeSymtab(bfd *abfd, asymbol **syms);
@@ -18,4 +19,42 @@ bfd_size_type getSectionSize(asection *section);
 bfd_vma getSectionVMA(bfd *abfd, asection *section);
 void mapOverSections(bfd *abfd, asection *section, void *data);
 void goMapOverSections(bfd *abfd, asection *section, void *data);
-bfd_boolean findInlinerInfo(bfd *abfd, const char **filename, const char **function, uint *line);
\ No newline at end of file
+bfd_boolean findInlinerInfo(bfd *abfd, const char **filename, const char **function, uint *line);
+bfd_format getFormat(bfd *abfd);
+bfd_boolean setArchMach(bfd *abfd, enum bfd_architecture arch, unsigned long mach);
+void setSectionVMA(bfd *abfd, asection *section, bfd_vma vma);
+void setSectionAlignment(bfd *abfd, asection *section, unsigned int power);
+bfd_boolean copyPrivateHeaderData(bfd *ibfd, bfd *obfd);
+bfd_boolean copyPrivateBfdData(bfd *ibfd, bfd *obfd);
+bfd_boolean copyPrivateSectionData(bfd *ibfd, asection *isection, bfd *obfd, asection *osection);
+void setReloc(bfd *abfd, asection *section, arelent **relocs, unsigned int count);
+asymbol *makeEmptySymbol(bfd *abfd);
+asection *absSectionPtr(void);
+int isUndSection(asection *section);
+int isAbsSection(asection *section);
+int isComSection(asection *section);
+int hasMap(bfd *abfd);
+void setHasMap(bfd *abfd, int has);
+int isThinArchive(bfd *abfd);
+void setThinArchive(bfd *abfd, int thin);
+bfd *getEltAtIndex(bfd *abfd, symindex index);
+int statArchElt(bfd *abfd, long long *size, unsigned int *mode, long long *mtime, int *uid, int *gid);
+char *sprintSymbol(bfd *abfd, asymbol *symbol);
+char *sprintPrivateBfdData(bfd *abfd, bfd_boolean *ok);
+void getSymbolInfo(bfd *abfd, asymbol *symbol, symbol_info *info);
+const char *getSymbolVersionString(bfd *abfd, asymbol *symbol, bfd_boolean *hidden);
+asymbol **getSyntheticSymtab(bfd *abfd, long symcount, asymbol **syms, long dynsymcount, asymbol **dynsyms, long *count, asymbol **data);
+bfd_boolean getFullSectionContents(bfd *abfd, asection *section, bfd_byte **buf, bfd_size_type *size);
+
+/* Elf_Internal_Phdr, as filled in by bfd_get_elf_phdrs; elf/internal.h is
+   not installed with the bfd headers */
+typedef struct {
+	unsigned long p_type;
+	unsigned long p_flags;
+	bfd_vma       p_offset;
+	bfd_vma       p_vaddr;
+	bfd_vma       p_paddr;
+	bfd_vma       p_filesz;
+	bfd_vma       p_memsz;
+	bfd_vma       p_align;
+} goElfPhdr;
@@ -0,0 +1,290 @@
+package bfd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Patch overwrites the bytes at a virtual address. Section restricts the
+// search to one section, which relocatable objects need since all their
+// sections start at address 0.
+type Patch struct {
+	VMA     VMA
+	Section string
+	Data    []byte
+}
+
+type PatchOptions struct {
+	// Force allows patching bytes that a relocation will rewrite
+	Force bool
+}
+
+// PatchRecord is the audit trail of an applied patch.
+type PatchRecord struct {
+	VMA     VMA
+	Section string
+	Offset  int64
+	Old     []byte
+	New     []byte
+}
+
+// isRelocatable reports whether abfd is an unlinked object, whose section
+// addresses overlap.
+func isRelocatable(abfd *File) bool {
+	return abfd.Flags()&(EXEC_P|DYNAMIC) == 0
+}
+
+// LocateVMA maps the size bytes at vma to the section holding them and their
+// position in the file. Relocatable objects are refused, use
+// LocateSectionVMA for those.
+func LocateVMA(abfd *File, vma VMA, size int64) (*Section, int64, error) {
+	if isRelocatable(abfd) {
+		return nil, 0, fmt.Errorf("%#x is ambiguous in relocatable %s, name the section", vma, abfd.Filename())
+	}
+	return locateVMA(abfd, "", vma, size)
+}
+
+// LocateSectionVMA is LocateVMA limited to the section called name.
+func LocateSectionVMA(abfd *File, name string, vma VMA, size int64) (*Section, int64, error) {
+	return locateVMA(abfd, name, vma, size)
+}
+
+func locateVMA(abfd *File, name string, vma VMA, size int64) (*Section, int64, error) {
+	for s := abfd.Sections(); s != nil; s = s.Next() {
+		if name != "" && s.Name() != name {
+			continue
+		}
+		if s.Flags()&SEC_LOAD == 0 || s.Flags()&SEC_HAS_CONTENTS == 0 {
+			continue
+		}
+		start := s.VMA()
+		end := start + VMA(s.Size())
+		if vma < start || vma >= end {
+			continue
+		}
+		if vma+VMA(size) > end || vma+VMA(size) < vma {
+			return nil, 0, fmt.Errorf("%#x+%d crosses the end of section %s", vma, size, s.Name())
+		}
+		if IsSectionCompressed(abfd, s) {
+			return nil, 0, fmt.Errorf("%#x is in compressed section %s", vma, s.Name())
+		}
+		return s, s.Filepos() + int64(vma-start), nil
+	}
+	if name != "" {
+		return nil, 0, fmt.Errorf("%#x is not in loaded section %s", vma, name)
+	}
+	return nil, 0, fmt.Errorf("%#x is not in any loaded section", vma)
+}
+
+// PatchFile applies patches to a copy of abfd written to oname, which may be
+// the file abfd was opened from; the rest of the file is left byte for byte
+// as it was. Patches that touch bytes covered by a static or dynamic
+// relocation are refused unless opts.Force is set.
+func PatchFile(abfd *File, oname string, patches []Patch, opts *PatchOptions) ([]PatchRecord, error) {
+	if opts == nil {
+		opts = &PatchOptions{}
+	}
+	if err := CheckFormat(abfd, Object); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, GetSize(abfd))
+	if _, err := abfd.ReadAt(buf, 0); err != nil {
+		return nil, err
+	}
+
+	var relocs []relocRange
+	if !opts.Force {
+		var err error
+		relocs, err = relocatedRanges(abfd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var records []PatchRecord
+	for _, p := range patches {
+		size := int64(len(p.Data))
+		var sec *Section
+		var off int64
+		var err error
+		if p.Section != "" {
+			sec, off, err = LocateSectionVMA(abfd, p.Section, p.VMA, size)
+		} else {
+			sec, off, err = LocateVMA(abfd, p.VMA, size)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if off < 0 || off+size > int64(len(buf)) {
+			return nil, fmt.Errorf("%#x: file position %#x+%d is out of bounds", p.VMA, off, size)
+		}
+		for _, r := range relocs {
+			if r.overlaps(sec, p.VMA, size) {
+				return nil, fmt.Errorf("%#x+%d overlaps %s relocation at %#x in %s", p.VMA, size, r.typ, r.vma, sec.Name())
+			}
+		}
+		for _, r := range records {
+			if off < r.Offset+int64(len(r.New)) && r.Offset < off+size {
+				return nil, fmt.Errorf("%#x+%d overlaps the patch at %#x", p.VMA, size, r.VMA)
+			}
+		}
+
+		records = append(records, PatchRecord{
+			VMA:     p.VMA,
+			Section: sec.Name(),
+			Offset:  off,
+			Old:     append([]byte(nil), buf[off:off+size]...),
+			New:     append([]byte(nil), p.Data...),
+		})
+	}
+
+	for _, r := range records {
+		copy(buf[r.Offset:], r.New)
+	}
+
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(abfd.Filename()); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := replaceFile(oname, buf, mode); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// replaceFile writes buf to name through a temporary file in the same
+// directory, so that name, which may be the file being patched, is left as
+// it was if anything goes wrong.
+func replaceFile(name string, buf []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "gobfd")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// relocRange is the bytes a relocation rewrites. Dynamic relocations have
+// no section, their addresses are unique on their own.
+type relocRange struct {
+	section *Section
+	vma     VMA
+	size    int
+	typ     string
+}
+
+func (r relocRange) overlaps(sec *Section, vma VMA, size int64) bool {
+	if r.section != nil && r.section != sec {
+		return false
+	}
+	return vma < r.vma+VMA(r.size) && r.vma < vma+VMA(size)
+}
+
+// relocatedRanges lists the addresses rewritten by relocations, either at
+// link time for objects or at load time for dynamic executables.
+func relocatedRanges(abfd *File) ([]relocRange, error) {
+	var ranges []relocRange
+
+	syms, err := slurpSymbols(abfd, false)
+	if err != nil {
+		return nil, err
+	}
+	if syms != nil {
+		defer syms.Free()
+	}
+	for s := abfd.Sections(); s != nil; s = s.Next() {
+		if s.Flags()&SEC_RELOC == 0 {
+			continue
+		}
+		storage := GetRelocUpperBound(abfd, s)
+		if storage <= 0 {
+			continue
+		}
+		table := AllocRelocTable(storage)
+		count, err := CanonicalizeReloc(abfd, s, table, syms)
+		if err != nil {
+			table.Free()
+			return nil, err
+		}
+		for i := int64(0); i < count; i++ {
+			r := table.Reloc(i)
+			ranges = append(ranges, relocRange{s, s.VMA() + r.Address(), r.Size(), r.Type()})
+		}
+		table.Free()
+	}
+
+	if abfd.Flags()&DYNAMIC == 0 {
+		return ranges, nil
+	}
+	storage := GetDynamicRelocUpperBound(abfd)
+	if storage <= 0 {
+		return ranges, nil
+	}
+	dynsyms, err := slurpSymbols(abfd, true)
+	if err != nil {
+		return nil, err
+	}
+	if dynsyms != nil {
+		defer dynsyms.Free()
+	}
+	table := AllocRelocTable(storage)
+	defer table.Free()
+	count, err := CanonicalizeDynamicReloc(abfd, table, dynsyms)
+	if err != nil {
+		return nil, err
+	}
+	for i := int64(0); i < count; i++ {
+		r := table.Reloc(i)
+		ranges = append(ranges, relocRange{nil, r.Address(), r.Size(), r.Type()})
+	}
+	return ranges, nil
+}
+
+// slurpSymbols reads the regular or dynamic symbol table, returning nil
+// if there is none.
+func slurpSymbols(abfd *File, dynamic bool) (*SymbolTable, error) {
+	var storage int64
+	if dynamic {
+		storage = GetDynamicSymtabUpperBound(abfd)
+	} else {
+		if abfd.Flags()&HAS_SYMS == 0 {
+			return nil, nil
+		}
+		storage = GetSymtabUpperBound(abfd)
+	}
+	if storage <= 0 {
+		return nil, nil
+	}
+
+	syms := AllocSymbolTable(storage)
+	var err error
+	if dynamic {
+		_, err = CanonicalizeDynamicSymtab(abfd, syms)
+	} else {
+		_, err = CanonicalizeSymtab(abfd, syms)
+	}
+	if err != nil {
+		syms.Free()
+		return nil, err
+	}
+	return syms, nil
+}
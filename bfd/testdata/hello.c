@@ -0,0 +1,16 @@
+/*
+ * hello-pie is built from this with
+ *
+ * gcc -Os -s -nostdlib -fno-asynchronous-unwind-tables -Wl,--no-as-needed \
+ *     -Wl,--enable-new-dtags,-rpath,/opt/lib -Wl,-z,noseparate-code \
+ *     -Wl,--build-id=none -o hello-pie hello.c -lc
+ */
+int puts(const char *);
+
+void
+_start(void)
+{
+	puts("hello");
+	for (;;)
+		;
+}
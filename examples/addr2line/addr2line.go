@@ -26,6 +26,8 @@ var (
 
 	pc            bfd.VMA
 	syms          *bfd.SymbolTable
+	dynsyms       *bfd.SymbolTable
+	synthetic     *bfd.SymbolTable
 	found         bool
 	xfilename     string
 	function      string
@@ -64,7 +66,7 @@ func process(file, sect, target string) {
 	ck(err)
 	defer bfd.Close(abfd)
 
-	abfd.SetFlags(abfd.Flags() | bfd.DECOMPRESS)
+	abfd.SetBfdUseFlags(abfd.Flags() | bfd.DECOMPRESS)
 
 	// bug in addr2line.c (?) seems to check against success and then erroring out
 	// we just ignore the return code here
@@ -81,6 +83,7 @@ func process(file, sect, target string) {
 	}
 
 	slurp(abfd)
+	slurpSynthetic(abfd)
 	translate(abfd, section)
 }
 
@@ -124,6 +127,43 @@ func slurp(abfd *bfd.File) {
 	}
 }
 
+// slurpSynthetic loads the synthetic symbols such as foo@plt, so addresses
+// in stubs without any symbols or line info of their own still resolve
+func slurpSynthetic(abfd *bfd.File) {
+	storage := bfd.GetDynamicSymtabUpperBound(abfd)
+	if storage > 0 {
+		dynsyms = bfd.AllocSymbolTable(storage)
+		if _, err := bfd.CanonicalizeDynamicSymtab(abfd, dynsyms); err != nil {
+			dynsyms.Free()
+			dynsyms = nil
+		}
+	}
+
+	table, err := bfd.GetSyntheticSymtab(abfd, syms, dynsyms)
+	if err != nil || table.Size() == 0 {
+		if table != nil {
+			table.Free()
+		}
+		return
+	}
+	synthetic = table
+}
+
+// findSyntheticSymbol resolves pc to the nearest synthetic symbol; they
+// carry no line info, only a function name
+func findSyntheticSymbol(section *bfd.Section) {
+	if synthetic == nil {
+		return
+	}
+	addr := pc
+	if section != nil {
+		addr += section.VMA()
+	}
+	if sym, _ := synthetic.NearestSymbol(addr); sym != nil {
+		found, xfilename, function, line, discriminator = true, "", sym.Name(), 0, 0
+	}
+}
+
 func translate(abfd *bfd.File, section *bfd.Section) {
 	addr := flag.Args()
 	readStdin := len(addr) == 0
@@ -166,6 +206,9 @@ func translate(abfd *bfd.File, section *bfd.Section) {
 		} else {
 			bfd.MapOverSections(abfd, findAddressInSection)
 		}
+		if !found {
+			findSyntheticSymbol(section)
+		}
 
 		if !found {
 			if *withFunctions {
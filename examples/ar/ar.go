@@ -0,0 +1,93 @@
+// ported from gnu ar
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+var (
+	deterministic = flag.Bool("D", false, "use zero for timestamps, uids and gids")
+	thin          = flag.Bool("T", false, "make a thin archive")
+	noSymbolMap   = flag.Bool("S", false, "do not build a symbol table")
+	target        = flag.String("target", "", "set target")
+	format        = flag.String("format", "", "archive format to write (gnu, bsd), picking a target that writes it")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("ar: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 2 {
+		usage()
+	}
+
+	opts := &bfd.ArchiveOptions{
+		Target:        *target,
+		Thin:          *thin,
+		Deterministic: *deterministic,
+		NoSymbolMap:   *noSymbolMap,
+	}
+	switch *format {
+	case "":
+	case "gnu":
+		opts.Format = bfd.ArchiveGNU
+	case "bsd":
+		opts.Format = bfd.ArchiveBSD
+	default:
+		log.Fatalf("unknown archive format %q", *format)
+	}
+
+	op, name, files := flag.Arg(0), flag.Arg(1), flag.Args()[2:]
+	if op == "s" {
+		ck(bfd.Ranlib(name, opts))
+		return
+	}
+
+	w, err := bfd.OpenArchiveWriter(name, opts)
+	ck(err)
+
+	var do func(string) error
+	switch op {
+	case "r":
+		do = w.Replace
+	case "q":
+		do = w.Append
+	case "d":
+		do = w.Delete
+	case "t":
+		for _, m := range w.Members() {
+			fmt.Println(m)
+		}
+		w.Abort()
+		return
+	default:
+		w.Abort()
+		usage()
+	}
+
+	for _, file := range files {
+		if err := do(file); err != nil {
+			w.Abort()
+			log.Fatal(err)
+		}
+	}
+	ck(w.Close())
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: [options] r|q|d|t|s archive [file ...]")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ck(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
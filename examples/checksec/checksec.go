@@ -0,0 +1,171 @@
+// checks the exploit mitigations of ELF files, like checksec
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+var (
+	jsonOutput = flag.Bool("json", false, "print the results as JSON")
+	require    = flag.String("require", "", "fail unless every file has these, comma separated: pie, nx, relro, full-relro, canary, fortify, cet, bti, no-rpath, no-wx")
+	target     = flag.String("target", "", "set target")
+
+	status int
+)
+
+type result struct {
+	File string
+	*bfd.ElfHardening
+	Missing []string `json:",omitempty"`
+}
+
+// checks maps the -require names to what they test
+var checks = map[string]func(h *bfd.ElfHardening) bool{
+	"pie":        func(h *bfd.ElfHardening) bool { return h.PIE || h.SharedObject },
+	"nx":         func(h *bfd.ElfHardening) bool { return h.NX },
+	"relro":      func(h *bfd.ElfHardening) bool { return h.Relro != bfd.RelroNone },
+	"full-relro": func(h *bfd.ElfHardening) bool { return h.Relro == bfd.RelroFull },
+	"canary":     func(h *bfd.ElfHardening) bool { return h.Canary },
+	"fortify":    func(h *bfd.ElfHardening) bool { return len(h.Fortified) > 0 },
+	"cet":        func(h *bfd.ElfHardening) bool { return h.Features.IBT && h.Features.SHSTK },
+	"bti":        func(h *bfd.ElfHardening) bool { return h.Features.BTI },
+	"no-rpath":   func(h *bfd.ElfHardening) bool { return len(h.Rpath) == 0 && len(h.Runpath) == 0 },
+	"no-wx":      func(h *bfd.ElfHardening) bool { return len(h.WXSegments) == 0 },
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("checksec: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	var required []string
+	if *require != "" {
+		required = strings.Split(*require, ",")
+		for _, name := range required {
+			if checks[name] == nil {
+				log.Fatalf("unknown requirement %q", name)
+			}
+		}
+	}
+
+	var results []*result
+	for _, name := range flag.Args() {
+		r := check(name, required)
+		if r == nil {
+			continue
+		}
+		if len(r.Missing) > 0 {
+			status = 1
+		}
+		results = append(results, r)
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		enc.Encode(results)
+	} else {
+		for _, r := range results {
+			printResult(r)
+		}
+	}
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: [options] elf-file ...")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ek(err error) bool {
+	if err != nil {
+		log.Print(err)
+		status = 1
+		return true
+	}
+	return false
+}
+
+func check(name string, required []string) *result {
+	abfd, err := bfd.Openr(name, *target)
+	if ek(err) {
+		return nil
+	}
+	defer bfd.Close(abfd)
+
+	if _, err := bfd.CheckFormatMatches(abfd, bfd.Object); err != nil {
+		ek(fmt.Errorf("%s: %v", name, err))
+		return nil
+	}
+	h, err := bfd.GetElfHardening(abfd)
+	if err != nil {
+		ek(fmt.Errorf("%s: %v", name, err))
+		return nil
+	}
+
+	r := &result{File: name, ElfHardening: h}
+	for _, req := range required {
+		if !checks[req](h) {
+			r.Missing = append(r.Missing, req)
+		}
+	}
+	return r
+}
+
+func yesno(b bool, yes, no string) string {
+	if b {
+		return yes
+	}
+	return no
+}
+
+func printResult(r *result) {
+	h := r.ElfHardening
+	field := func(name, value string) {
+		fmt.Printf("  %-10s %s\n", name+":", value)
+	}
+
+	fmt.Printf("%s:\n", r.File)
+	pie := yesno(h.PIE, "PIE enabled", "No PIE")
+	if h.SharedObject {
+		pie = "DSO"
+	}
+	field("PIE", pie)
+	field("NX", yesno(h.NX, "NX enabled", "NX disabled"))
+	field("RELRO", h.Relro.String())
+	field("Canary", yesno(h.Canary, "Canary found", "No canary found"))
+	field("FORTIFY", yesno(len(h.Fortified) > 0, strings.Join(h.Fortified, " "), "No fortified functions"))
+
+	var features []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{{h.Features.IBT, "IBT"}, {h.Features.SHSTK, "SHSTK"}, {h.Features.BTI, "BTI"}, {h.Features.PAC, "PAC"}} {
+		if f.set {
+			features = append(features, f.name)
+		}
+	}
+	field("CET/BTI", yesno(len(features) > 0, strings.Join(features, " "), "None"))
+	field("RPATH", yesno(len(h.Rpath) > 0, strings.Join(h.Rpath, ":"), "No RPATH"))
+	field("RUNPATH", yesno(len(h.Runpath) > 0, strings.Join(h.Runpath, ":"), "No RUNPATH"))
+	if len(h.WXSegments) > 0 {
+		field("W+X", fmt.Sprintf("segments %v are writable and executable", h.WXSegments))
+	} else {
+		field("W+X", "None")
+	}
+	if len(r.Missing) > 0 {
+		field("Missing", strings.Join(r.Missing, ", "))
+	}
+}
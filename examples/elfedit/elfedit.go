@@ -0,0 +1,194 @@
+// ported from gnu elfedit
+package main
+
+import (
+	"debug/elf"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+type strList []string
+
+func (s *strList) String() string     { return strings.Join(*s, ",") }
+func (s *strList) Set(v string) error { *s = append(*s, v); return nil }
+
+var (
+	inputMach   = flag.String("input-mach", "", "only edit files for this machine")
+	outputMach  = flag.String("output-mach", "", "set the machine")
+	inputType   = flag.String("input-type", "", "only edit files of this type: rel, exec or dyn")
+	outputType  = flag.String("output-type", "", "set the type: rel, exec or dyn")
+	inputOSABI  = flag.String("input-osabi", "", "only edit files with this OSABI")
+	outputOSABI = flag.String("output-osabi", "", "set the OSABI")
+	output      = flag.String("output", "", "write the result to file instead of in place")
+
+	enableX86  strList
+	disableX86 strList
+
+	status int
+)
+
+func init() {
+	flag.Var(&enableX86, "enable-x86-feature", "set an x86 feature: ibt or shstk")
+	flag.Var(&disableX86, "disable-x86-feature", "clear an x86 feature: ibt or shstk")
+}
+
+// machine names elfedit takes that are not the EM_ names
+var machineAliases = map[string]elf.Machine{
+	"i386":   elf.EM_386,
+	"x86-64": elf.EM_X86_64,
+	"l1om":   elf.EM_L10M,
+	"k1om":   elf.EM_K10M,
+}
+
+var x86Features = map[string]uint32{
+	"ibt":   bfd.ElfPropertyX86Feature1IBT,
+	"shstk": bfd.ElfPropertyX86Feature1SHSTK,
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("elfedit: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+	if *output != "" && flag.NArg() > 1 {
+		log.Fatal("-output only works with one file")
+	}
+
+	opts := &bfd.ElfEditOptions{}
+	var err error
+	if opts.InputMachine, err = parseMachine(*inputMach); err != nil {
+		log.Fatal(err)
+	}
+	if opts.OutputMachine, err = parseMachine(*outputMach); err != nil {
+		log.Fatal(err)
+	}
+	if opts.InputType, err = parseType(*inputType); err != nil {
+		log.Fatal(err)
+	}
+	if opts.OutputType, err = parseType(*outputType); err != nil {
+		log.Fatal(err)
+	}
+	if opts.InputOSABI, err = parseOSABI(*inputOSABI); err != nil {
+		log.Fatal(err)
+	}
+	if opts.OutputOSABI, err = parseOSABI(*outputOSABI); err != nil {
+		log.Fatal(err)
+	}
+	if opts.EnableX86Features, err = parseFeatures(enableX86); err != nil {
+		log.Fatal(err)
+	}
+	if opts.DisableX86Features, err = parseFeatures(disableX86); err != nil {
+		log.Fatal(err)
+	}
+	if opts.OutputMachine == nil && opts.OutputType == nil && opts.OutputOSABI == nil &&
+		opts.EnableX86Features|opts.DisableX86Features == 0 {
+		log.Fatal("nothing to edit")
+	}
+
+	for _, name := range flag.Args() {
+		oname := name
+		if *output != "" {
+			oname = *output
+		}
+		ek(bfd.EditElfFile(name, oname, opts))
+	}
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: [options] elf-file ...")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ek(err error) bool {
+	if err != nil {
+		log.Print(err)
+		status = 1
+		return true
+	}
+	return false
+}
+
+// lookup finds name among the n values of a debug/elf enum, by its name
+// with or without prefix, or by number.
+func lookup(name, prefix string, n int, str func(int) string) (int, bool) {
+	if v, err := strconv.ParseUint(name, 0, 16); err == nil && int(v) < n {
+		return int(v), true
+	}
+	for i := 0; i < n; i++ {
+		s := str(i)
+		if strings.EqualFold(s, name) || strings.EqualFold(strings.TrimPrefix(s, prefix), name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func parseMachine(name string) (*elf.Machine, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if m, ok := machineAliases[strings.ToLower(name)]; ok {
+		return &m, nil
+	}
+	v, ok := lookup(strings.Replace(name, "-", "_", -1), "EM_", 1<<16, func(i int) string { return elf.Machine(i).String() })
+	if !ok {
+		return nil, fmt.Errorf("unknown machine %q", name)
+	}
+	m := elf.Machine(v)
+	return &m, nil
+}
+
+func parseType(name string) (*elf.Type, error) {
+	var t elf.Type
+	switch strings.ToLower(name) {
+	case "":
+		return nil, nil
+	case "rel":
+		t = elf.ET_REL
+	case "exec":
+		t = elf.ET_EXEC
+	case "dyn":
+		t = elf.ET_DYN
+	default:
+		return nil, fmt.Errorf("unknown type %q", name)
+	}
+	return &t, nil
+}
+
+func parseOSABI(name string) (*elf.OSABI, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(name, "gnu") {
+		name = "linux"
+	}
+	v, ok := lookup(name, "ELFOSABI_", 256, func(i int) string { return elf.OSABI(i).String() })
+	if !ok {
+		return nil, fmt.Errorf("unknown OSABI %q", name)
+	}
+	osabi := elf.OSABI(v)
+	return &osabi, nil
+}
+
+func parseFeatures(names []string) (uint32, error) {
+	var bits uint32
+	for _, name := range names {
+		bit, ok := x86Features[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown x86 feature %q", name)
+		}
+		bits |= bit
+	}
+	return bits, nil
+}
@@ -0,0 +1,140 @@
+// lists the shared library dependencies of ELF files like ldd, without running them
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+var (
+	sysroot     = flag.String("sysroot", "", "look for libraries under this directory")
+	libraryPath = flag.String("library-path", "", "colon separated directories to search, like LD_LIBRARY_PATH")
+	ldSoConf    = flag.String("ld-so-conf", "", "read search directories from this file in the sysroot instead of /etc/ld.so.conf")
+	unresolved  = flag.Bool("r", false, "report missing versions and undefined symbols")
+	tree        = flag.Bool("tree", false, "print the dependencies as a tree")
+	target      = flag.String("target", "", "set target")
+
+	status int
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("ldd: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	opts := &bfd.ElfResolveOptions{
+		Sysroot:  *sysroot,
+		LdSoConf: *ldSoConf,
+		Target:   *target,
+	}
+	if *libraryPath != "" {
+		opts.LibraryPath = filepath.SplitList(*libraryPath)
+	}
+
+	for _, name := range flag.Args() {
+		if flag.NArg() > 1 {
+			fmt.Printf("%s:\n", name)
+		}
+		ldd(name, opts)
+	}
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: [options] elf-file ...")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ek(err error) bool {
+	if err != nil {
+		log.Print(err)
+		status = 1
+		return true
+	}
+	return false
+}
+
+func ldd(name string, opts *bfd.ElfResolveOptions) {
+	deps, err := bfd.ResolveElfDependencies(name, opts)
+	if ek(err) {
+		return
+	}
+	if len(deps.Missing) > 0 {
+		status = 1
+	}
+
+	if *tree {
+		printTree(deps)
+	} else {
+		for _, lib := range deps.Objects[1:] {
+			fmt.Printf("\t%s => %s\n", lib.Name, lib.Path)
+		}
+		for _, lib := range deps.Missing {
+			fmt.Printf("\t%s => not found\n", lib.Name)
+		}
+	}
+
+	if !*unresolved {
+		return
+	}
+	for _, obj := range deps.Objects {
+		for _, v := range obj.MissingVersions {
+			fmt.Printf("\t%s: version `%s' not found (required by %s)\n", v.Library, v.Version, obj.Path)
+			status = 1
+		}
+	}
+	for _, obj := range deps.Objects {
+		for _, sym := range obj.Unresolved {
+			name := sym.Name
+			if sym.Version != "" {
+				name += "@" + sym.Version
+			}
+			if sym.Library != "" {
+				name += " (" + sym.Library + ")"
+			}
+			fmt.Printf("undefined symbol: %s\t(%s)\n", name, obj.Path)
+			status = 1
+		}
+	}
+}
+
+// printTree prints every object under the one that needed it first, and
+// libraries already listed elsewhere by name only.
+func printTree(deps *bfd.ElfDependencies) {
+	children := make(map[*bfd.ElfLibrary][]*bfd.ElfLibrary)
+	byName := make(map[string]*bfd.ElfLibrary)
+	for _, lib := range append(deps.Objects[1:], deps.Missing...) {
+		children[lib.Parent] = append(children[lib.Parent], lib)
+		byName[lib.Name] = lib
+	}
+
+	var walk func(obj *bfd.ElfLibrary, depth int)
+	walk = func(obj *bfd.ElfLibrary, depth int) {
+		indent := strings.Repeat("    ", depth)
+		for _, needed := range obj.Needed {
+			lib := byName[needed]
+			switch {
+			case lib == nil || lib.Parent != obj:
+				fmt.Printf("%s\t%s\n", indent, needed)
+			case lib.Path == "":
+				fmt.Printf("%s\t%s => not found\n", indent, needed)
+			default:
+				fmt.Printf("%s\t%s => %s\n", indent, needed, lib.Path)
+				walk(lib, depth+1)
+			}
+		}
+	}
+	fmt.Println(deps.Objects[0].Path)
+	walk(deps.Objects[0], 0)
+}
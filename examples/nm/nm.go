@@ -0,0 +1,450 @@
+// ported from gnu nm
+package main
+
+import (
+	"debug/elf"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+	"github.com/qeedquan/go-binutils/iberty/demangle"
+)
+
+var (
+	debugSyms     = flag.Bool("a", false, "display debugger-only symbols")
+	fileName      = flag.Bool("A", false, "print name of the input file before every symbol")
+	printFileName = flag.Bool("o", false, "same as -A")
+	bsdFormat     = flag.Bool("B", false, "same as -f bsd")
+	demangleNames = flag.Bool("C", false, "decode low-level symbol names into user-level names")
+	demangler     = flag.String("demangle-style", "", "use demangling style")
+	dynamic       = flag.Bool("D", false, "display dynamic symbols instead of normal symbols")
+	definedOnly   = flag.Bool("defined-only", false, "display only defined symbols")
+	format        = flag.String("f", "bsd", "use the output format (bsd, sysv, posix)")
+	externOnly    = flag.Bool("g", false, "display only external symbols")
+	numericSort   = flag.Bool("n", false, "sort symbols numerically by address")
+	versionSort   = flag.Bool("v", false, "same as -n")
+	noSort        = flag.Bool("p", false, "do not sort the symbols")
+	posixFormat   = flag.Bool("P", false, "same as -f posix")
+	reverseSort   = flag.Bool("r", false, "reverse the sense of the sort")
+	printSize     = flag.Bool("S", false, "print size of defined symbols")
+	printArmap    = flag.Bool("s", false, "include index for symbols from archive members")
+	sizeSort      = flag.Bool("size-sort", false, "sort symbols by size")
+	radix         = flag.String("t", "x", "use radix for printing symbol values (d, o, x)")
+	target        = flag.String("target", "", "specify the target object format")
+	undefinedOnly = flag.Bool("u", false, "display only undefined symbols")
+
+	multipleFiles bool
+	status        int
+)
+
+type symbol struct {
+	sym  *bfd.Symbol
+	elf  *bfd.ElfSymbol
+	info bfd.SymbolInfo
+	name string
+	size bfd.VMA
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("nm: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *bsdFormat {
+		*format = "bsd"
+	}
+	if *posixFormat {
+		*format = "posix"
+	}
+	switch *format {
+	case "bsd", "sysv", "posix":
+	default:
+		log.Fatalf("%s: invalid output format", *format)
+	}
+	switch *radix {
+	case "d", "o", "x":
+	default:
+		log.Fatalf("%s: invalid radix", *radix)
+	}
+	if *printFileName {
+		*fileName = true
+	}
+	if *versionSort {
+		*numericSort = true
+	}
+	if *undefinedOnly && *definedOnly {
+		log.Fatal("cannot use -u and -defined-only together")
+	}
+	if *demangler != "" {
+		style := demangle.CplusNameToStyle(*demangler)
+		if style == demangle.Unknown {
+			log.Fatalf("unknown demangling style %q", *demangler)
+		}
+		demangle.CplusSetStyle(style)
+		*demangleNames = true
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"a.out"}
+	}
+	multipleFiles = len(args) > 1
+	for _, name := range args {
+		displayFile(name)
+	}
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: [options] [file ...]")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ek(err error) bool {
+	if err != nil {
+		log.Print(err)
+		status = 1
+		return true
+	}
+	return false
+}
+
+func displayFile(name string) {
+	abfd, err := bfd.Openr(name, *target)
+	if ek(err) {
+		return
+	}
+	defer bfd.Close(abfd)
+
+	if bfd.CheckFormat(abfd, bfd.Archive) == nil {
+		displayArchive(abfd)
+		return
+	}
+	if _, err := bfd.CheckFormatMatches(abfd, bfd.Object); err != nil {
+		ek(fmt.Errorf("%s: %v", name, err))
+		return
+	}
+	if !*fileName && multipleFiles && *format == "bsd" {
+		fmt.Printf("\n%s:\n", name)
+	}
+	displayRel(abfd, nil)
+}
+
+func displayArchive(archive *bfd.File) {
+	if *printArmap {
+		printArchiveMap(archive)
+	}
+
+	var member *bfd.File
+	var err error
+	for {
+		member, err = bfd.OpenrNextArchivedFile(archive, member)
+		if err == bfd.ErrNoMoreArchivedFiles {
+			break
+		}
+		if ek(err) {
+			return
+		}
+		if _, err := bfd.CheckFormatMatches(member, bfd.Object); err != nil {
+			ek(fmt.Errorf("%s(%s): %v", archive.Filename(), member.Filename(), err))
+			continue
+		}
+		if !*fileName && *format == "bsd" {
+			fmt.Printf("\n%s:\n", member.Filename())
+		}
+		displayRel(member, archive)
+	}
+}
+
+func printArchiveMap(archive *bfd.File) {
+	syms, err := bfd.ReadArmap(archive)
+	if err == bfd.ErrNoArmap {
+		return
+	}
+	if ek(err) {
+		return
+	}
+	fmt.Printf("\nArchive index:\n")
+	for _, s := range syms {
+		fmt.Printf("%s in %s\n", s.Name, s.Member.Filename())
+	}
+	fmt.Println()
+}
+
+func displayRel(abfd, archive *bfd.File) {
+	if abfd.Flags()&bfd.HAS_SYMS == 0 && !*dynamic {
+		ek(fmt.Errorf("%s: no symbols", abfd.Filename()))
+		return
+	}
+
+	var storage int64
+	if *dynamic {
+		storage = bfd.GetDynamicSymtabUpperBound(abfd)
+	} else {
+		storage = bfd.GetSymtabUpperBound(abfd)
+	}
+	if storage < 0 {
+		ek(fmt.Errorf("%s: %v", abfd.Filename(), bfd.GetError()))
+		return
+	}
+	if storage == 0 {
+		ek(fmt.Errorf("%s: no symbols", abfd.Filename()))
+		return
+	}
+
+	table := bfd.AllocSymbolTable(storage)
+	defer table.Free()
+	var count int64
+	var err error
+	if *dynamic {
+		count, err = bfd.CanonicalizeDynamicSymtab(abfd, table)
+	} else {
+		count, err = bfd.CanonicalizeSymtab(abfd, table)
+	}
+	if ek(err) {
+		return
+	}
+
+	var elfSyms []bfd.ElfSymbol
+	if bfd.GetFlavor(abfd) == bfd.TargetElfFlavor {
+		elfSyms, err = bfd.GetElfSymbols(abfd, *dynamic)
+		if ek(err) || int64(len(elfSyms)) != count {
+			elfSyms = nil
+		}
+	}
+
+	syms := filterSymbols(abfd, table, count, elfSyms)
+	if *printSize || *sizeSort {
+		computeSizes(syms)
+	}
+	sortSymbols(syms)
+	printSymbols(abfd, archive, syms)
+}
+
+func filterSymbols(abfd *bfd.File, table *bfd.SymbolTable, count int64, elfSyms []bfd.ElfSymbol) []*symbol {
+	var syms []*symbol
+	for i := int64(0); i < count; i++ {
+		sym := table.Symbol(i)
+		if sym == nil {
+			continue
+		}
+
+		sec := sym.Section()
+		und := bfd.IsUndSection(sec)
+		keep := true
+		switch {
+		case *undefinedOnly:
+			keep = und
+		case *externOnly:
+			keep = sym.Flags()&(bfd.BSF_GLOBAL|bfd.BSF_WEAK|bfd.BSF_GNU_UNIQUE) != 0 ||
+				und || bfd.IsComSection(sec)
+		}
+		if keep && !*debugSyms && sym.Flags()&bfd.BSF_DEBUGGING != 0 {
+			keep = false
+		}
+		if keep && *sizeSort && (bfd.IsAbsSection(sec) || und) {
+			keep = false
+		}
+		if keep && *definedOnly && und {
+			keep = false
+		}
+		if !keep {
+			continue
+		}
+
+		s := &symbol{sym: sym, info: bfd.GetSymbolInfo(abfd, sym)}
+		if elfSyms != nil {
+			s.elf = &elfSyms[i]
+		}
+		s.name = s.info.Name
+		if *demangleNames {
+			if name := demangle.Cplus(s.name, demangle.ANSI|demangle.PARAMS); name != "" {
+				s.name = name
+			}
+		}
+		if *dynamic {
+			s.name += symbolVersion(abfd, sym, und)
+		}
+		syms = append(syms, s)
+	}
+	return syms
+}
+
+// symbolVersion returns the @VERSION or @@VERSION suffix of a dynamic
+// symbol; references to other objects and hidden symbols take a single @
+func symbolVersion(abfd *bfd.File, sym *bfd.Symbol, und bool) string {
+	if bfd.GetFlavor(abfd) != bfd.TargetElfFlavor {
+		return ""
+	}
+	version, hidden := bfd.GetSymbolVersionString(abfd, sym)
+	switch {
+	case version == "" || version == "Base":
+		return ""
+	case hidden || und:
+		return "@" + version
+	}
+	return "@@" + version
+}
+
+// computeSizes takes the size of ELF symbols from the symbol table; others
+// are sized as the distance to the next symbol of their section, or to the
+// end of the section for the last one
+func computeSizes(syms []*symbol) {
+	bysec := make(map[*bfd.Section][]*symbol)
+	for _, s := range syms {
+		if s.elf != nil {
+			s.size = bfd.VMA(s.elf.Size)
+			continue
+		}
+		sec := s.sym.Section()
+		if bfd.IsUndSection(sec) || bfd.IsAbsSection(sec) || bfd.IsComSection(sec) {
+			continue
+		}
+		bysec[sec] = append(bysec[sec], s)
+	}
+	for sec, list := range bysec {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].sym.Address() < list[j].sym.Address()
+		})
+		end := sec.VMA() + bfd.VMA(sec.Size())
+		for i, s := range list {
+			next := end
+			for j := i + 1; j < len(list); j++ {
+				if addr := list[j].sym.Address(); addr > s.sym.Address() {
+					next = addr
+					break
+				}
+			}
+			if next > s.sym.Address() {
+				s.size = next - s.sym.Address()
+			}
+		}
+	}
+}
+
+func sortSymbols(syms []*symbol) {
+	var less func(a, b *symbol) bool
+	switch {
+	case *noSort:
+		return
+	case *sizeSort:
+		less = func(a, b *symbol) bool {
+			if a.size != b.size {
+				return a.size < b.size
+			}
+			return a.name < b.name
+		}
+	case *numericSort:
+		less = func(a, b *symbol) bool {
+			aund, bund := bfd.IsUndSection(a.sym.Section()), bfd.IsUndSection(b.sym.Section())
+			if aund != bund {
+				return aund
+			}
+			if a.info.Value != b.info.Value {
+				return a.info.Value < b.info.Value
+			}
+			return a.name < b.name
+		}
+	default:
+		less = func(a, b *symbol) bool { return a.name < b.name }
+	}
+	sort.SliceStable(syms, func(i, j int) bool {
+		if *reverseSort {
+			return less(syms[j], syms[i])
+		}
+		return less(syms[i], syms[j])
+	})
+}
+
+func printSymbols(abfd, archive *bfd.File, syms []*symbol) {
+	width := 8
+	if bfd.GetArchSize(abfd) == 64 {
+		width = 16
+	}
+
+	prefix := ""
+	if *fileName {
+		if archive != nil {
+			prefix = archive.Filename() + ":"
+		}
+		prefix += abfd.Filename() + ":"
+	}
+
+	if *format == "sysv" {
+		name := abfd.Filename()
+		if archive != nil {
+			name = archive.Filename() + "[" + name + "]"
+		}
+		fmt.Printf("\n\nSymbols from %s:\n\n", name)
+		fmt.Printf("Name                  Value%*sClass        Type         Size%*sLine  Section\n\n", width-5, "", width-3, "")
+	}
+
+	for _, s := range syms {
+		und := bfd.IsUndSection(s.sym.Section())
+		value := formatValue(s.info.Value, width)
+		size := ""
+		if s.size != 0 {
+			size = formatValue(s.size, width)
+		}
+
+		switch *format {
+		case "bsd":
+			if und {
+				value = strings.Repeat(" ", width)
+			}
+			if *printSize && size != "" {
+				fmt.Printf("%s%s %s %c %s\n", prefix, value, size, s.info.Type, s.name)
+			} else {
+				fmt.Printf("%s%s %c %s\n", prefix, value, s.info.Type, s.name)
+			}
+
+		case "sysv":
+			if und {
+				value = strings.Repeat(" ", width)
+			}
+			if size == "" {
+				size = strings.Repeat(" ", width)
+			}
+			fmt.Printf("%s%-20s|%s|   %c  |%18s|%s|     |%s\n", prefix, s.name, value, s.info.Type, symbolType(s), size, sectionName(s))
+
+		case "posix":
+			if und {
+				fmt.Printf("%s%s %c\n", prefix, s.name, s.info.Type)
+			} else {
+				fmt.Printf("%s%s %c %s %s\n", prefix, s.name, s.info.Type, value, size)
+			}
+		}
+	}
+}
+
+// symbolType names the ELF symbol type for the sysv format
+func symbolType(s *symbol) string {
+	if s.elf == nil || s.elf.Type == elf.STT_NOTYPE {
+		return ""
+	}
+	return strings.TrimPrefix(s.elf.Type.String(), "STT_")
+}
+
+func sectionName(s *symbol) string {
+	sec := s.sym.Section()
+	if bfd.IsUndSection(sec) {
+		return "*UND*"
+	}
+	return sec.Name()
+}
+
+func formatValue(v bfd.VMA, width int) string {
+	switch *radix {
+	case "d":
+		return fmt.Sprintf("%0*d", width, uint64(v))
+	case "o":
+		return fmt.Sprintf("%0*o", width, uint64(v))
+	}
+	return fmt.Sprintf("%0*x", width, uint64(v))
+}
@@ -0,0 +1,331 @@
+// ported from gnu objcopy
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+var (
+	inputTarget  = flag.String("I", "", "input target")
+	outputTarget = flag.String("O", "", "output target")
+	compress     = flag.String("compress-debug-sections", "", "compress debug sections (none, zlib, zlib-gnu, zlib-gabi)")
+	decompress   = flag.Bool("decompress-debug-sections", false, "decompress debug sections")
+	verbose      = flag.Bool("v", false, "report the size change of each debug section")
+	prefixSyms   = flag.String("prefix-symbols", "", "add prefix to the start of every symbol name")
+	redefineFile = flag.String("redefine-syms", "", "redefine symbols listed as \"old new\" pairs in file")
+	changeAddrs  = flag.String("change-addresses", "", "change the address of all sections and the start address by incr")
+	changeStart  = flag.String("change-start", "", "change the start address by incr")
+	setStart     = flag.String("set-start", "", "set the start address to addr")
+
+	redefineSyms   strList
+	localizeSyms   strList
+	globalizeSyms  strList
+	weakenSyms     strList
+	keepGlobalSyms strList
+	addSyms        strList
+	changeSecAddr  strList
+	changeSecLMA   strList
+	changeSecVMA   strList
+	setSecAlign    strList
+
+	opts bfd.CopyOptions
+)
+
+type strList []string
+
+func (s *strList) String() string     { return strings.Join(*s, ",") }
+func (s *strList) Set(v string) error { *s = append(*s, v); return nil }
+
+func init() {
+	flag.Var(&redefineSyms, "redefine-sym", "redefine symbol old=new")
+	flag.Var(&localizeSyms, "localize-symbol", "force symbol to be marked as local")
+	flag.Var(&globalizeSyms, "globalize-symbol", "force symbol to be marked as global")
+	flag.Var(&weakenSyms, "weaken-symbol", "force symbol to be marked as weak")
+	flag.Var(&keepGlobalSyms, "keep-global-symbol", "localize all global symbols except this one")
+	flag.Var(&addSyms, "add-symbol", "add symbol name=[section:]value[,flags]")
+	flag.Var(&changeSecAddr, "change-section-address", "change the VMA and LMA of section name{=,+,-}val")
+	flag.Var(&changeSecLMA, "change-section-lma", "change the LMA of section name{=,+,-}val")
+	flag.Var(&changeSecVMA, "change-section-vma", "change the VMA of section name{=,+,-}val")
+	flag.Var(&setSecAlign, "set-section-alignment", "set the alignment of section name=align")
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("objcopy: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		usage()
+	}
+
+	opts.InputTarget = *inputTarget
+	opts.OutputTarget = *outputTarget
+	switch *compress {
+	case "":
+	case "none":
+		opts.DebugSections = bfd.CompressNone
+	case "zlib", "zlib-gabi":
+		opts.DebugSections = bfd.CompressGABI
+	case "zlib-gnu":
+		opts.DebugSections = bfd.CompressGNU
+	default:
+		log.Fatalf("unknown debug section compression type %q", *compress)
+	}
+	if *decompress {
+		if *compress != "" {
+			log.Fatal("cannot both compress and decompress debug sections")
+		}
+		opts.DebugSections = bfd.Decompress
+	}
+
+	opts.RedefineSyms = make(map[string]string)
+	for _, arg := range redefineSyms {
+		i := strings.IndexByte(arg, '=')
+		if i < 0 {
+			log.Fatalf("bad format for -redefine-sym %q", arg)
+		}
+		redefine(arg[:i], arg[i+1:])
+	}
+	if *redefineFile != "" {
+		readRedefineSyms(*redefineFile)
+	}
+	opts.PrefixSymbols = *prefixSyms
+	opts.LocalizeSymbols = localizeSyms
+	opts.GlobalizeSymbols = globalizeSyms
+	opts.WeakenSymbols = weakenSyms
+	opts.KeepGlobalSymbols = keepGlobalSyms
+	for _, arg := range addSyms {
+		opts.AddSymbols = append(opts.AddSymbols, parseAddSymbol(arg))
+	}
+
+	if *changeAddrs != "" {
+		incr := parseVMA(*changeAddrs)
+		opts.ChangeAddresses = incr
+		opts.ChangeStart += incr
+	}
+	if *changeStart != "" {
+		opts.ChangeStart += parseVMA(*changeStart)
+	}
+	if *setStart != "" {
+		opts.SetStart = true
+		opts.StartAddress = parseVMA(*setStart)
+	}
+	for _, arg := range changeSecAddr {
+		addSectionChange(arg, bfd.SetVMA|bfd.SetLMA, bfd.AlterVMA|bfd.AlterLMA)
+	}
+	for _, arg := range changeSecLMA {
+		addSectionChange(arg, bfd.SetLMA, bfd.AlterLMA)
+	}
+	for _, arg := range changeSecVMA {
+		addSectionChange(arg, bfd.SetVMA, bfd.AlterVMA)
+	}
+	for _, arg := range setSecAlign {
+		i := strings.IndexByte(arg, '=')
+		if i < 0 {
+			log.Fatalf("bad format for -set-section-alignment %q", arg)
+		}
+		align := parseVMA(arg[i+1:])
+		if align == 0 || align&(align-1) != 0 {
+			log.Fatalf("alignment %q for section %q is not a power of two", arg[i+1:], arg[:i])
+		}
+		power := uint(0)
+		for ; align > 1; align >>= 1 {
+			power++
+		}
+		opts.SectionChanges = append(opts.SectionChanges, bfd.SectionChange{
+			Pattern:   arg[:i],
+			Context:   bfd.SetAlignment,
+			Alignment: power,
+		})
+	}
+
+	iname := flag.Arg(0)
+	oname := flag.Arg(1)
+	if oname == "" {
+		copyInPlace(iname)
+	} else {
+		ck(bfd.CopyFile(iname, oname, &opts))
+		report(iname, oname)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: [options] in-file [out-file]")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ck(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func copyInPlace(name string) {
+	f, err := os.CreateTemp(filepath.Dir(name), "objcopy")
+	ck(err)
+	tmp := f.Name()
+	f.Close()
+
+	err = bfd.CopyFile(name, tmp, &opts)
+	if err != nil {
+		os.Remove(tmp)
+		log.Fatal(err)
+	}
+	report(name, tmp)
+
+	fi, err := os.Stat(name)
+	if err == nil {
+		os.Chmod(tmp, fi.Mode())
+	}
+	ck(os.Rename(tmp, name))
+}
+
+func report(iname, oname string) {
+	if !*verbose {
+		return
+	}
+
+	stats, err := bfd.CompareDebugSections(iname, oname)
+	ck(err)
+	for _, s := range stats {
+		name := s.Name
+		if s.Member != "" {
+			name = s.Member + "(" + name + ")"
+		}
+		fmt.Printf("%s: %s -> %s: %d -> %d bytes (%d saved)\n",
+			iname, name, s.OutName, s.Size, s.OutSize, s.Saved())
+	}
+}
+
+func redefine(from, to string) {
+	if _, found := opts.RedefineSyms[from]; found {
+		log.Fatalf("multiple redefinition of symbol %q", from)
+	}
+	opts.RedefineSyms[from] = to
+}
+
+func readRedefineSyms(name string) {
+	f, err := os.Open(name)
+	ck(err)
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 0:
+		case 2:
+			redefine(fields[0], fields[1])
+		default:
+			log.Fatalf("%s:%d: garbage found at end of line", name, lineno)
+		}
+	}
+	ck(scanner.Err())
+}
+
+var symFlags = map[string]bfd.Flagword{
+	"local":             bfd.BSF_LOCAL,
+	"global":            bfd.BSF_GLOBAL,
+	"export":            bfd.BSF_EXPORT,
+	"weak":              bfd.BSF_WEAK,
+	"debug":             bfd.BSF_DEBUGGING,
+	"function":          bfd.BSF_FUNCTION,
+	"file":              bfd.BSF_FILE,
+	"section":           bfd.BSF_SECTION_SYM,
+	"object":            bfd.BSF_OBJECT,
+	"synthetic":         bfd.BSF_SYNTHETIC,
+	"indirect-function": bfd.BSF_GNU_INDIRECT_FUNCTION | bfd.BSF_FUNCTION,
+	"unique":            bfd.BSF_GNU_UNIQUE,
+	"warning":           bfd.BSF_WARNING,
+	"indirect":          bfd.BSF_INDIRECT,
+	"constructor":       bfd.BSF_CONSTRUCTOR,
+}
+
+// parseAddSymbol parses name=[section:]value[,flags]
+func parseAddSymbol(arg string) bfd.AddSymbol {
+	var sym bfd.AddSymbol
+
+	i := strings.IndexByte(arg, '=')
+	if i < 0 {
+		log.Fatalf("bad format for -add-symbol %q", arg)
+	}
+	sym.Name, arg = arg[:i], arg[i+1:]
+
+	fields := strings.Split(arg, ",")
+	value := fields[0]
+	if i := strings.LastIndexByte(value, ':'); i >= 0 {
+		sym.Section, value = value[:i], value[i+1:]
+	}
+	vma, rest := bfd.ScanVMA(value, 0)
+	if value == "" || rest != "" {
+		log.Fatalf("bad value %q for symbol %q", value, sym.Name)
+	}
+	sym.Value = vma
+
+	for _, f := range fields[1:] {
+		bit, found := symFlags[f]
+		if !found {
+			log.Fatalf("unrecognized symbol flag %q for symbol %q", f, sym.Name)
+		}
+		sym.Flags |= bit
+	}
+	return sym
+}
+
+func parseVMA(str string) bfd.VMA {
+	neg := strings.HasPrefix(str, "-")
+	if neg {
+		str = str[1:]
+	}
+	vma, rest := bfd.ScanVMA(str, 0)
+	if str == "" || rest != "" {
+		log.Fatalf("bad address %q", str)
+	}
+	if neg {
+		vma = -vma
+	}
+	return vma
+}
+
+// addSectionChange parses name{=,+,-}val
+func addSectionChange(arg string, set, alter bfd.SectionContext) {
+	// like objcopy, look for = before + and -, which section names can hold
+	i := strings.IndexByte(arg, '=')
+	if i < 0 {
+		i = strings.IndexByte(arg, '+')
+	}
+	if i < 0 {
+		i = strings.IndexByte(arg, '-')
+	}
+	if i < 0 {
+		log.Fatalf("bad format for section change %q", arg)
+	}
+
+	val := parseVMA(arg[i+1:])
+	context := alter
+	switch arg[i] {
+	case '=':
+		context = set
+	case '-':
+		val = -val
+	}
+	opts.SectionChanges = append(opts.SectionChanges, bfd.SectionChange{
+		Pattern: arg[:i],
+		Context: context,
+		VMA:     val,
+		LMA:     val,
+	})
+}
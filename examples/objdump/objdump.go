@@ -0,0 +1,626 @@
+// ported from gnu objdump
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+	"github.com/qeedquan/go-binutils/iberty/demangle"
+	"github.com/qeedquan/go-binutils/opcodes"
+)
+
+var (
+	disassemble      = flag.Bool("d", false, "display assembler contents of executable sections")
+	disassembleAll   = flag.Bool("D", false, "display assembler contents of all sections")
+	fileHeaders      = flag.Bool("f", false, "display the contents of the overall file header")
+	privateHeaders   = flag.Bool("p", false, "display object format specific file header contents")
+	sectionHeaders   = flag.Bool("h", false, "display the contents of the section headers")
+	relocs           = flag.Bool("r", false, "display the relocation entries in the file")
+	dynamicRelocs    = flag.Bool("R", false, "display the dynamic relocation entries in the file")
+	fullContents     = flag.Bool("s", false, "display the full contents of all sections requested")
+	symbols          = flag.Bool("t", false, "display the contents of the symbol table")
+	dynamicSymbols   = flag.Bool("T", false, "display the contents of the dynamic symbol table")
+	allHeaders       = flag.Bool("x", false, "display the contents of all headers")
+	sectionName      = flag.String("j", "", "only display information for section name")
+	disassemblerOpts = flag.String("M", "", "pass options on to the disassembler")
+	target           = flag.String("b", "", "set target")
+	demangleNames    = flag.Bool("C", false, "decode mangled symbol names")
+	showRawInsn      = flag.Bool("show-raw-insn", true, "display instruction bytes alongside the disassembly")
+	lineNumbers      = flag.Bool("l", false, "include line numbers and filenames in output")
+	source           = flag.Bool("S", false, "intermix source code with disassembly")
+	inlines          = flag.Bool("inlines", false, "print all inlines for source line (with -l)")
+
+	includeDirs strList
+	pathMaps    strList
+	status      int
+)
+
+type strList []string
+
+func (s *strList) String() string     { return strings.Join(*s, ",") }
+func (s *strList) Set(v string) error { *s = append(*s, v); return nil }
+
+func init() {
+	flag.Var(&includeDirs, "I", "add dir to the search list for source files")
+	flag.Var(&pathMaps, "path-map", "replace the prefix old of source file names with new, as old=new")
+}
+
+// objdump keeps one symbol table per file being dumped
+type dumper struct {
+	abfd    *bfd.File
+	syms    *bfd.SymbolTable
+	dynsyms *bfd.SymbolTable
+	synsyms *bfd.SymbolTable
+	sorted  []*bfd.Symbol
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("objdump: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *allHeaders {
+		*fileHeaders = true
+		*privateHeaders = true
+		*sectionHeaders = true
+		*relocs = true
+		*symbols = true
+	}
+	if *source {
+		*disassemble = true
+	}
+	if *disassembleAll {
+		*disassemble = true
+	}
+	if !(*disassemble || *fileHeaders || *privateHeaders || *sectionHeaders ||
+		*relocs || *dynamicRelocs || *fullContents || *symbols || *dynamicSymbols) {
+		usage()
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"a.out"}
+	}
+	for _, name := range args {
+		display(name)
+	}
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: -d|-D|-f|-h|-p|-r|-R|-s|-S|-t|-T|-x [options] [file ...]")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ek(err error) bool {
+	if err != nil {
+		log.Print(err)
+		status = 1
+		return true
+	}
+	return false
+}
+
+func display(name string) {
+	abfd, err := bfd.Openr(name, *target)
+	if ek(err) {
+		return
+	}
+	defer bfd.Close(abfd)
+
+	abfd.SetBfdUseFlags(abfd.Flags() | bfd.DECOMPRESS)
+	if bfd.CheckFormat(abfd, bfd.Archive) == nil {
+		fmt.Printf("In archive %s:\n", name)
+		var member *bfd.File
+		for {
+			member, err = bfd.OpenrNextArchivedFile(abfd, member)
+			if err == bfd.ErrNoMoreArchivedFiles {
+				break
+			}
+			if ek(err) {
+				return
+			}
+			member.SetBfdUseFlags(member.Flags() | bfd.DECOMPRESS)
+			displayObject(member)
+		}
+		return
+	}
+	displayObject(abfd)
+}
+
+func displayObject(abfd *bfd.File) {
+	if _, err := bfd.CheckFormatMatches(abfd, bfd.Object); err != nil {
+		if bfd.CheckFormat(abfd, bfd.Core) != nil {
+			ek(fmt.Errorf("%s: %v", abfd.Filename(), err))
+			return
+		}
+	}
+
+	fmt.Printf("\n%s:     file format %s\n", abfd.Filename(), abfd.Xvec().Name())
+
+	d := &dumper{abfd: abfd}
+	defer d.free()
+	if *symbols || *relocs || *disassemble {
+		d.syms = slurp(abfd, false)
+	}
+	if *dynamicSymbols || *dynamicRelocs || *disassemble {
+		d.dynsyms = slurp(abfd, true)
+	}
+
+	if *fileHeaders {
+		d.dumpFileHeader()
+	}
+	if *privateHeaders {
+		d.dumpPrivateHeaders()
+	}
+	fmt.Println()
+	if *sectionHeaders {
+		d.dumpSectionHeaders()
+	}
+	if *symbols {
+		d.dumpSymbols(d.syms, false)
+	}
+	if *dynamicSymbols {
+		d.dumpSymbols(d.dynsyms, true)
+	}
+	if *relocs {
+		d.dumpRelocs()
+	}
+	if *dynamicRelocs {
+		d.dumpDynamicRelocs()
+	}
+	if *fullContents {
+		d.dumpContents()
+	}
+	if *disassemble {
+		d.disassemble()
+	}
+}
+
+func (d *dumper) free() {
+	if d.syms != nil {
+		d.syms.Free()
+	}
+	if d.synsyms != nil {
+		d.synsyms.Free()
+	}
+	if d.dynsyms != nil {
+		d.dynsyms.Free()
+	}
+}
+
+func slurp(abfd *bfd.File, dynamic bool) *bfd.SymbolTable {
+	var storage int64
+	if dynamic {
+		storage = bfd.GetDynamicSymtabUpperBound(abfd)
+	} else {
+		if abfd.Flags()&bfd.HAS_SYMS == 0 {
+			return nil
+		}
+		storage = bfd.GetSymtabUpperBound(abfd)
+	}
+	if storage <= 0 {
+		return nil
+	}
+
+	var err error
+	syms := bfd.AllocSymbolTable(storage)
+	if dynamic {
+		_, err = bfd.CanonicalizeDynamicSymtab(abfd, syms)
+	} else {
+		_, err = bfd.CanonicalizeSymtab(abfd, syms)
+	}
+	if ek(err) {
+		syms.Free()
+		return nil
+	}
+	return syms
+}
+
+// wanted reports whether section was picked by -j
+func wanted(section *bfd.Section) bool {
+	return *sectionName == "" || section.Name() == *sectionName
+}
+
+var fileFlags = []struct {
+	flag int
+	name string
+}{
+	{bfd.HAS_RELOC, "HAS_RELOC"},
+	{bfd.EXEC_P, "EXEC_P"},
+	{bfd.HAS_LINENO, "HAS_LINENO"},
+	{bfd.HAS_DEBUG, "HAS_DEBUG"},
+	{bfd.HAS_SYMS, "HAS_SYMS"},
+	{bfd.HAS_LOCALS, "HAS_LOCALS"},
+	{bfd.DYNAMIC, "DYNAMIC"},
+	{bfd.WP_TEXT, "WP_TEXT"},
+	{bfd.D_PAGED, "D_PAGED"},
+	{bfd.IS_RELAXABLE, "BFD_IS_RELAXABLE"},
+}
+
+func (d *dumper) dumpFileHeader() {
+	flags := d.abfd.Flags() & bfd.ApplicableFileFlags(d.abfd)
+	fmt.Printf("architecture: %s, flags 0x%08x:\n", bfd.PrintableName(d.abfd), flags)
+
+	var names []string
+	for _, f := range fileFlags {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	fmt.Println(strings.Join(names, ", "))
+	fmt.Printf("start address 0x%s\n", bfd.SprintfVMA(d.abfd, bfd.GetStartAddress(d.abfd)))
+}
+
+func (d *dumper) dumpPrivateHeaders() {
+	str, err := bfd.SprintPrivateBfdData(d.abfd)
+	fmt.Print(str)
+	ek(err)
+}
+
+var sectionFlags = []struct {
+	flag bfd.Flagword
+	name string
+}{
+	{bfd.SEC_HAS_CONTENTS, "CONTENTS"},
+	{bfd.SEC_ALLOC, "ALLOC"},
+	{bfd.SEC_CONSTRUCTOR, "CONSTRUCTOR"},
+	{bfd.SEC_LOAD, "LOAD"},
+	{bfd.SEC_RELOC, "RELOC"},
+	{bfd.SEC_READONLY, "READONLY"},
+	{bfd.SEC_CODE, "CODE"},
+	{bfd.SEC_DATA, "DATA"},
+	{bfd.SEC_ROM, "ROM"},
+	{bfd.SEC_DEBUGGING, "DEBUGGING"},
+	{bfd.SEC_NEVER_LOAD, "NEVER_LOAD"},
+	{bfd.SEC_EXCLUDE, "EXCLUDE"},
+	{bfd.SEC_SORT_ENTRIES, "SORT_ENTRIES"},
+	{bfd.SEC_SMALL_DATA, "SMALL_DATA"},
+	{bfd.SEC_THREAD_LOCAL, "THREAD_LOCAL"},
+	{bfd.SEC_GROUP, "GROUP"},
+}
+
+func (d *dumper) dumpSectionHeaders() {
+	width := len(bfd.SprintfVMA(d.abfd, 0))
+	fmt.Println("Sections:")
+	fmt.Printf("Idx Name          Size      %-*s  %-*s  File off  Algn\n", width, "VMA", width, "LMA")
+	for s := d.abfd.Sections(); s != nil; s = s.Next() {
+		if !wanted(s) {
+			continue
+		}
+		fmt.Printf("%3d %-13s %08x  %s  %s  %08x  2**%d\n",
+			s.Index(), s.Name(), s.Size(),
+			bfd.SprintfVMA(d.abfd, s.VMA()), bfd.SprintfVMA(d.abfd, s.LMA()),
+			s.Filepos(), s.Alignment())
+
+		var names []string
+		for _, f := range sectionFlags {
+			if s.Flags()&f.flag != 0 {
+				names = append(names, f.name)
+			}
+		}
+		if s.Flags()&bfd.SEC_LINK_ONCE != 0 {
+			switch s.Flags() & bfd.SEC_LINK_DUPLICATES {
+			case bfd.SEC_LINK_DUPLICATES_DISCARD:
+				names = append(names, "LINK_ONCE_DISCARD")
+			case bfd.SEC_LINK_DUPLICATES_ONE_ONLY:
+				names = append(names, "LINK_ONCE_ONE_ONLY")
+			case bfd.SEC_LINK_DUPLICATES_SAME_SIZE:
+				names = append(names, "LINK_ONCE_SAME_SIZE")
+			case bfd.SEC_LINK_DUPLICATES_SAME_CONTENTS:
+				names = append(names, "LINK_ONCE_SAME_CONTENTS")
+			}
+		}
+		fmt.Printf("                  %s\n", strings.Join(names, ", "))
+	}
+}
+
+func (d *dumper) dumpSymbols(syms *bfd.SymbolTable, dynamic bool) {
+	if dynamic {
+		fmt.Println("DYNAMIC SYMBOL TABLE:")
+	} else {
+		fmt.Println("SYMBOL TABLE:")
+	}
+	if syms == nil {
+		fmt.Printf("no symbols\n\n")
+		return
+	}
+	for i := int64(0); i < syms.Size(); i++ {
+		sym := syms.Symbol(i)
+		if sym == nil {
+			continue
+		}
+		line := bfd.SprintSymbol(d.abfd, sym)
+		if *demangleNames {
+			if name := d.demangle(sym.Name()); name != sym.Name() {
+				line = strings.TrimSuffix(line, sym.Name()) + name
+			}
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
+
+func (d *dumper) demangle(name string) string {
+	if !*demangleNames {
+		return name
+	}
+	if alloc := bfd.Demangle(d.abfd, name, demangle.ANSI|demangle.PARAMS); alloc != "" {
+		return alloc
+	}
+	return name
+}
+
+func (d *dumper) dumpRelocs() {
+	if d.abfd.Flags()&bfd.DYNAMIC != 0 && d.abfd.Flags()&bfd.HAS_RELOC == 0 {
+		return
+	}
+	for s := d.abfd.Sections(); s != nil; s = s.Next() {
+		if !wanted(s) || s.Flags()&bfd.SEC_RELOC == 0 {
+			continue
+		}
+		storage := bfd.GetRelocUpperBound(d.abfd, s)
+		if storage <= 0 {
+			continue
+		}
+		table := bfd.AllocRelocTable(storage)
+		count, err := bfd.CanonicalizeReloc(d.abfd, s, table, d.syms)
+		if !ek(err) && count > 0 {
+			fmt.Printf("RELOCATION RECORDS FOR [%s]:\n", s.Name())
+			d.printRelocs(table, count)
+		}
+		table.Free()
+	}
+}
+
+func (d *dumper) dumpDynamicRelocs() {
+	fmt.Println("DYNAMIC RELOCATION RECORDS")
+	storage := bfd.GetDynamicRelocUpperBound(d.abfd)
+	if storage <= 0 {
+		fmt.Printf(" (none)\n\n")
+		return
+	}
+	table := bfd.AllocRelocTable(storage)
+	defer table.Free()
+	count, err := bfd.CanonicalizeDynamicReloc(d.abfd, table, d.dynsyms)
+	if ek(err) {
+		return
+	}
+	d.printRelocs(table, count)
+}
+
+func (d *dumper) printRelocs(table *bfd.RelocTable, count int64) {
+	width := len(bfd.SprintfVMA(d.abfd, 0))
+	fmt.Printf("%-*s %-16s  %s\n", width, "OFFSET", "TYPE", "VALUE")
+	for i := int64(0); i < count; i++ {
+		r := table.Reloc(i)
+		typ := r.Type()
+		if typ == "" {
+			typ = "*unknown*"
+		}
+		fmt.Printf("%s %-16s  %s\n", bfd.SprintfVMA(d.abfd, r.Address()), typ, d.relocValue(r))
+	}
+	fmt.Printf("\n\n")
+}
+
+func (d *dumper) relocValue(r *bfd.Reloc) string {
+	value := "*ABS*"
+	if sym := r.Symbol(); sym != nil {
+		value = d.demangle(sym.Name())
+		if value == "" {
+			value = sym.Section().Name()
+		}
+	}
+	switch addend := r.Addend(); {
+	case int64(addend) < 0:
+		value += "-0x" + bfd.SprintfVMA(d.abfd, -addend)
+	case addend != 0:
+		value += "+0x" + bfd.SprintfVMA(d.abfd, addend)
+	}
+	return value
+}
+
+func (d *dumper) dumpContents() {
+	for s := d.abfd.Sections(); s != nil; s = s.Next() {
+		if !wanted(s) || s.Flags()&bfd.SEC_HAS_CONTENTS == 0 || s.Size() == 0 {
+			continue
+		}
+		fmt.Printf("Contents of section %s:\n", s.Name())
+
+		// debug sections are decompressed, which only reading them whole does
+		buf, err := bfd.GetFullSectionContents(d.abfd, s)
+		if ek(err) {
+			continue
+		}
+
+		width := len(fmt.Sprintf("%x", uint64(s.VMA())+uint64(len(buf))))
+		if width < 4 {
+			width = 4
+		}
+		for off := 0; off < len(buf); off += 16 {
+			fmt.Printf(" %0*x ", width, uint64(s.VMA())+uint64(off))
+			for i := off; i < off+16; i++ {
+				if i < len(buf) {
+					fmt.Printf("%02x", buf[i])
+				} else {
+					fmt.Printf("  ")
+				}
+				if i%4 == 3 {
+					fmt.Printf(" ")
+				}
+			}
+			fmt.Printf(" ")
+			for i := off; i < off+16 && i < len(buf); i++ {
+				if buf[i] >= ' ' && buf[i] < 0x7f {
+					fmt.Printf("%c", buf[i])
+				} else {
+					fmt.Printf(".")
+				}
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// sortSymbols collects the symbols disassembly labels come from, along with
+// the synthetic ones bfd makes for PLT entries, ordered by address
+func (d *dumper) sortSymbols() {
+	syms := d.syms
+	if syms == nil {
+		syms = d.dynsyms
+	}
+	if synsyms, err := bfd.GetSyntheticSymtab(d.abfd, d.syms, d.dynsyms); err == nil {
+		d.synsyms = synsyms
+	}
+	for _, table := range []*bfd.SymbolTable{syms, d.synsyms} {
+		if table == nil {
+			continue
+		}
+		for i := int64(0); i < table.Size(); i++ {
+			sym := table.Symbol(i)
+			if sym == nil || sym.Section() == nil || bfd.IsUndSection(sym.Section()) {
+				continue
+			}
+			if sym.Flags()&(bfd.BSF_FILE|bfd.BSF_DEBUGGING) != 0 {
+				continue
+			}
+			d.sorted = append(d.sorted, sym)
+		}
+	}
+	sort.SliceStable(d.sorted, func(i, j int) bool {
+		a, b := d.sorted[i], d.sorted[j]
+		if a.Address() != b.Address() {
+			return a.Address() < b.Address()
+		}
+		// prefer real symbols over section symbols at the same address
+		return a.Flags()&bfd.BSF_SECTION_SYM == 0 && b.Flags()&bfd.BSF_SECTION_SYM != 0
+	})
+}
+
+// findSymbol returns the symbol addr falls in, preferring one from the
+// section holding addr.
+func (d *dumper) findSymbol(addr bfd.VMA) *bfd.Symbol {
+	i := sort.Search(len(d.sorted), func(i int) bool { return d.sorted[i].Address() > addr })
+	var fallback *bfd.Symbol
+	for i--; i >= 0; i-- {
+		sym := d.sorted[i]
+		sec := sym.Section()
+		if addr < sec.VMA()+bfd.VMA(sec.Size()) || bfd.IsAbsSection(sec) {
+			return sym
+		}
+		if fallback == nil {
+			fallback = sym
+		}
+	}
+	return fallback
+}
+
+func (d *dumper) lookup(addr bfd.VMA) (string, bfd.VMA, bool) {
+	sym := d.findSymbol(addr)
+	if sym == nil {
+		return "", 0, false
+	}
+	return d.demangle(sym.Name()), addr - sym.Address(), true
+}
+
+func (d *dumper) disassemble() {
+	dis, err := opcodes.New(d.abfd, *disassemblerOpts)
+	if ek(err) {
+		return
+	}
+	defer dis.Close()
+
+	d.sortSymbols()
+	dis.SetSymbolFunc(d.lookup)
+
+	for s := d.abfd.Sections(); s != nil; s = s.Next() {
+		if !wanted(s) || s.Flags()&bfd.SEC_HAS_CONTENTS == 0 || s.Size() == 0 {
+			continue
+		}
+		if !*disassembleAll && *sectionName == "" && s.Flags()&bfd.SEC_CODE == 0 {
+			continue
+		}
+		d.disassembleSection(dis, s)
+	}
+}
+
+// starts returns the symbols that begin inside section, in address order
+func (d *dumper) starts(section *bfd.Section) []*bfd.Symbol {
+	var syms []*bfd.Symbol
+	for _, sym := range d.sorted {
+		if sym.Section() == section && sym.Flags()&bfd.BSF_SECTION_SYM == 0 {
+			if len(syms) == 0 || syms[len(syms)-1].Address() != sym.Address() {
+				syms = append(syms, sym)
+			}
+		}
+	}
+	return syms
+}
+
+func (d *dumper) disassembleSection(dis *opcodes.Disassembler, section *bfd.Section) {
+	fmt.Printf("\nDisassembly of section %s:\n", section.Name())
+
+	insns, err := dis.DisassembleSection(section)
+	labels := d.starts(section)
+	end := section.VMA() + bfd.VMA(section.Size())
+	width := len(fmt.Sprintf("%x", uint64(end)))
+	st := &lineState{printed: make(map[string]int64)}
+
+	for i, insn := range insns {
+		label := ""
+		for len(labels) > 0 && labels[0].Address() <= insn.Address {
+			if labels[0].Address() == insn.Address {
+				label = d.demangle(labels[0].Name())
+			}
+			labels = labels[1:]
+		}
+		if label == "" && i == 0 {
+			label = section.Name()
+			if name, off, ok := d.lookup(insn.Address); ok && off == 0 {
+				label = name
+			}
+		}
+		if label != "" {
+			fmt.Printf("\n%s <%s>:\n", bfd.SprintfVMA(d.abfd, insn.Address), label)
+		}
+		if *lineNumbers || *source {
+			d.show(st, section, insn.Address)
+		}
+		d.printInsn(insn, width)
+	}
+	ek(err)
+}
+
+func (d *dumper) printInsn(insn opcodes.Instruction, width int) {
+	text := strings.TrimRight(insn.Text, " ")
+	if !*showRawInsn {
+		fmt.Printf("%*x:\t%s\n", width+2, uint64(insn.Address), text)
+		return
+	}
+
+	const perLine = 7
+	bytes := insn.Bytes
+	for line := 0; len(bytes) > 0; line++ {
+		n := len(bytes)
+		if n > perLine {
+			n = perLine
+		}
+		var hex []string
+		for _, b := range bytes[:n] {
+			hex = append(hex, fmt.Sprintf("%02x", b))
+		}
+		addr := uint64(insn.Address) + uint64(line*perLine)
+		if line == 0 {
+			fmt.Printf("%*x:\t%-*s\t%s\n", width+2, addr, perLine*3-1, strings.Join(hex, " "), text)
+		} else {
+			fmt.Printf("%*x:\t%s\n", width+2, addr, strings.Join(hex, " "))
+		}
+		bytes = bytes[n:]
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+// sources caches the lines of every source file shown so far; a nil entry
+// means the file could not be found
+type sources struct {
+	files map[string][]string
+}
+
+// lineState remembers what show printed last, so only changes get printed
+type lineState struct {
+	filename string
+	function string
+	line     int64
+	printed  map[string]int64
+}
+
+var cache = sources{files: make(map[string][]string)}
+
+// resolve maps a file name recorded in the debug info to where it is on
+// this machine: the -path-map substitutions are applied first, then each -I
+// directory is tried with the base name of the file
+func resolve(name string) string {
+	for _, m := range pathMaps {
+		i := strings.IndexByte(m, '=')
+		if i < 0 {
+			continue
+		}
+		if from := m[:i]; strings.HasPrefix(name, from) {
+			name = m[i+1:] + name[len(from):]
+			break
+		}
+	}
+	if _, err := os.Stat(name); err == nil {
+		return name
+	}
+	for _, dir := range includeDirs {
+		path := filepath.Join(dir, filepath.Base(name))
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return name
+}
+
+func (s *sources) lines(name string) []string {
+	if lines, found := s.files[name]; found {
+		return lines
+	}
+
+	var lines []string
+	if f, err := os.Open(resolve(name)); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+	}
+	s.files[name] = lines
+	return lines
+}
+
+// printSource prints the lines of file up to line; if the previous line
+// printed from the file is a little before it, the lines in between are
+// printed too, the way objdump -S does for straight line code
+func (st *lineState) printSource(filename string, line int64) {
+	lines := cache.lines(filename)
+	if line <= 0 || line > int64(len(lines)) {
+		return
+	}
+
+	start := line
+	if last, found := st.printed[filename]; found && last < line && line-last <= 8 {
+		start = last + 1
+	}
+	for i := start; i <= line; i++ {
+		fmt.Println(lines[i-1])
+	}
+	st.printed[filename] = line
+}
+
+// show prints the location of addr when it differs from the one before:
+// the function name and file:line for -l, the source text for -S and, with
+// -inlines, the chain of callers addr got inlined into
+func (d *dumper) show(st *lineState, section *bfd.Section, addr bfd.VMA) {
+	syms := d.syms
+	if syms == nil {
+		syms = d.dynsyms
+	}
+	found, filename, function, line, discriminator := bfd.FindNearestLineDiscriminator(d.abfd, section, syms, addr-section.VMA())
+	if !found || filename == "" && function == "" {
+		return
+	}
+	if filename == st.filename && line == st.line && function == st.function {
+		return
+	}
+
+	if *lineNumbers {
+		if function != "" && function != st.function {
+			fmt.Printf("%s():\n", d.demangle(function))
+		}
+		if line > 0 {
+			if discriminator != 0 {
+				fmt.Printf("%s:%d (discriminator %d)\n", filename, line, discriminator)
+			} else {
+				fmt.Printf("%s:%d\n", filename, line)
+			}
+		}
+		if *inlines {
+			for {
+				found, file2, function2, line2 := bfd.FindInlinerInfo(d.abfd)
+				if !found {
+					break
+				}
+				fmt.Printf("inlined by %s:%d (%s)\n", file2, line2, d.demangle(function2))
+			}
+		}
+	}
+	if *source && filename != "" && (filename != st.filename || line != st.line) {
+		st.printSource(filename, line)
+	}
+
+	st.filename, st.function, st.line = filename, function, line
+}
@@ -0,0 +1,79 @@
+// patches bytes at virtual addresses of an executable or object
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+var (
+	output = flag.String("o", "", "output file")
+	target = flag.String("b", "", "set target")
+	force  = flag.Bool("f", false, "allow patching relocated bytes")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("patch: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 2 || *output == "" {
+		usage()
+	}
+
+	var patches []bfd.Patch
+	for _, arg := range flag.Args()[1:] {
+		patches = append(patches, parsePatch(arg))
+	}
+
+	abfd, err := bfd.Openr(flag.Arg(0), *target)
+	ck(err)
+	defer bfd.Close(abfd)
+
+	records, err := bfd.PatchFile(abfd, *output, patches, &bfd.PatchOptions{Force: *force})
+	ck(err)
+	for _, r := range records {
+		fmt.Printf("%#x in %s (file offset %#x): %x -> %x\n", r.VMA, r.Section, r.Offset, r.Old, r.New)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: -o out-file [options] file [section:]vma=hexbytes ...")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ck(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parsePatch(arg string) bfd.Patch {
+	i := strings.IndexByte(arg, '=')
+	if i < 0 {
+		log.Fatalf("bad patch %q, want [section:]vma=hexbytes", arg)
+	}
+
+	// relocatable objects have every section at 0, so they need the section
+	var section string
+	addr := arg[:i]
+	if j := strings.LastIndexByte(addr, ':'); j >= 0 {
+		section, addr = addr[:j], addr[j+1:]
+	}
+	vma, rest := bfd.ScanVMA(addr, 16)
+	if addr == "" || rest != "" {
+		log.Fatalf("bad address in patch %q", arg)
+	}
+	data, err := hex.DecodeString(arg[i+1:])
+	if err != nil || len(data) == 0 {
+		log.Fatalf("bad bytes in patch %q", arg)
+	}
+	return bfd.Patch{VMA: vma, Section: section, Data: data}
+}
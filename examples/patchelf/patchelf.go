@@ -0,0 +1,109 @@
+// edits the dynamic linking information of ELF files, like patchelf
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+type strList []string
+
+func (s *strList) String() string     { return strings.Join(*s, ",") }
+func (s *strList) Set(v string) error { *s = append(*s, v); return nil }
+
+var (
+	interp      = flag.String("set-interpreter", "", "set the program interpreter")
+	soname      = flag.String("set-soname", "", "set DT_SONAME")
+	rpath       = flag.String("set-rpath", "", "set DT_RUNPATH, or DT_RPATH with -force-rpath")
+	forceRpath  = flag.Bool("force-rpath", false, "set DT_RPATH instead of DT_RUNPATH")
+	removeRpath = flag.Bool("remove-rpath", false, "remove DT_RPATH and DT_RUNPATH")
+	output      = flag.String("output", "", "write the result to file instead of in place")
+
+	replaceNeeded strList
+	addNeeded     strList
+	removeNeeded  strList
+
+	status int
+)
+
+func init() {
+	flag.Var(&replaceNeeded, "replace-needed", "replace a DT_NEEDED library, as old=new")
+	flag.Var(&addNeeded, "add-needed", "add a DT_NEEDED library")
+	flag.Var(&removeNeeded, "remove-needed", "remove a DT_NEEDED library")
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("patchelf: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 || (*output != "" && flag.NArg() > 1) {
+		usage()
+	}
+
+	opts := &bfd.ElfPatchOptions{
+		Interp:       *interp,
+		Soname:       *soname,
+		RemoveRpath:  *removeRpath,
+		AddNeeded:    addNeeded,
+		RemoveNeeded: removeNeeded,
+	}
+	if flagSet("set-rpath") {
+		// like patchelf, a new search path replaces both kinds
+		opts.RemoveRpath = true
+		if *forceRpath {
+			opts.Rpath = strings.Split(*rpath, ":")
+		} else {
+			opts.Runpath = strings.Split(*rpath, ":")
+		}
+	}
+	for _, r := range replaceNeeded {
+		i := strings.IndexByte(r, '=')
+		if i < 0 {
+			log.Fatalf("invalid replacement %q, want old=new", r)
+		}
+		if opts.ReplaceNeeded == nil {
+			opts.ReplaceNeeded = make(map[string]string)
+		}
+		opts.ReplaceNeeded[r[:i]] = r[i+1:]
+	}
+
+	for _, name := range flag.Args() {
+		oname := name
+		if *output != "" {
+			oname = *output
+		}
+		ek(bfd.PatchElfFile(name, oname, opts))
+	}
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: [options] elf-file ...")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ek(err error) bool {
+	if err != nil {
+		log.Print(err)
+		status = 1
+		return true
+	}
+	return false
+}
+
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
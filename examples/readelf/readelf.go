@@ -0,0 +1,493 @@
+// ported from gnu readelf
+package main
+
+import (
+	"bytes"
+	"debug/elf"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+var (
+	all            = flag.Bool("a", false, "equivalent to -h -l -S -s -d -V -n")
+	dynamic        = flag.Bool("d", false, "display the dynamic section")
+	notes          = flag.Bool("n", false, "display the notes")
+	symbols        = flag.Bool("s", false, "display the symbol table")
+	versionInfo    = flag.Bool("V", false, "display the version sections")
+	fileHeader     = flag.Bool("h", false, "display the ELF file header")
+	programHeaders = flag.Bool("l", false, "display the program headers")
+	sectionHeaders = flag.Bool("S", false, "display the sections' header")
+	target         = flag.String("target", "", "set target")
+
+	status int
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("readelf: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *all {
+		*fileHeader = true
+		*programHeaders = true
+		*sectionHeaders = true
+		*symbols = true
+		*dynamic = true
+		*versionInfo = true
+		*notes = true
+	}
+	if flag.NArg() == 0 || !(*fileHeader || *programHeaders || *sectionHeaders || *symbols || *dynamic || *versionInfo || *notes) {
+		usage()
+	}
+
+	for _, name := range flag.Args() {
+		processFile(name)
+	}
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: -a|-h|-l|-S|-s|-d|-V|-n [options] elf-file ...")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ek(err error) bool {
+	if err != nil {
+		log.Print(err)
+		status = 1
+		return true
+	}
+	return false
+}
+
+func processFile(name string) {
+	abfd, err := bfd.Openr(name, *target)
+	if ek(err) {
+		return
+	}
+	defer bfd.Close(abfd)
+
+	if bfd.CheckFormat(abfd, bfd.Archive) == nil {
+		var member *bfd.File
+		for {
+			member, err = bfd.OpenrNextArchivedFile(abfd, member)
+			if err == bfd.ErrNoMoreArchivedFiles {
+				break
+			}
+			if ek(err) {
+				return
+			}
+			fmt.Printf("\nFile: %s(%s)\n", name, member.Filename())
+			processObject(member)
+		}
+		return
+	}
+	if flag.NArg() > 1 {
+		fmt.Printf("\nFile: %s\n", name)
+	}
+	processObject(abfd)
+}
+
+func processObject(abfd *bfd.File) {
+	if _, err := bfd.CheckFormatMatches(abfd, bfd.Object); err != nil {
+		ek(fmt.Errorf("%s: %v", abfd.Filename(), err))
+		return
+	}
+	if bfd.GetFlavor(abfd) != bfd.TargetElfFlavor {
+		ek(fmt.Errorf("%s: not an ELF file", abfd.Filename()))
+		return
+	}
+
+	hdr, err := bfd.GetElfHeader(abfd)
+	if ek(err) {
+		return
+	}
+	sections, err := bfd.GetElfSectionHeaders(abfd)
+	if ek(err) {
+		return
+	}
+
+	if *fileHeader {
+		printFileHeader(hdr)
+	}
+	if *sectionHeaders {
+		printSectionHeaders(hdr, sections)
+	}
+	if *programHeaders {
+		phdrs, err := bfd.GetElfPhdrs(abfd)
+		if ek(err) {
+			return
+		}
+		printProgramHeaders(abfd, hdr, phdrs, sections)
+	}
+	if *dynamic {
+		dyn, err := bfd.GetElfDynamic(abfd)
+		if ek(err) {
+			return
+		}
+		printDynamic(hdr, dyn)
+	}
+	if *symbols {
+		for _, dynamic := range []bool{true, false} {
+			syms, err := bfd.GetElfSymbols(abfd, dynamic)
+			if ek(err) {
+				return
+			}
+			printSymbols(hdr, syms, dynamic)
+		}
+	}
+	if *versionInfo {
+		ver, err := bfd.GetElfVersions(abfd)
+		if ek(err) {
+			return
+		}
+		printVersions(ver)
+	}
+	if *notes {
+		list, err := bfd.GetElfNotes(abfd)
+		if ek(err) {
+			return
+		}
+		printNotes(list)
+	}
+}
+
+// trim drops the C prefix debug/elf puts on constant names
+func trim(s fmt.Stringer, prefix string) string {
+	return strings.TrimPrefix(s.String(), prefix)
+}
+
+func printFileHeader(hdr *bfd.ElfHeader) {
+	fmt.Println("ELF Header:")
+	fmt.Printf("  Magic:  ")
+	for _, b := range hdr.Ident {
+		fmt.Printf(" %02x", b)
+	}
+	fmt.Println()
+
+	field := func(name, format string, args ...interface{}) {
+		fmt.Printf("  %-35s%s\n", name+":", fmt.Sprintf(format, args...))
+	}
+	field("Class", "%s", trim(hdr.Class, "ELFCLASS"))
+	field("Data", "%s", trim(hdr.Data, "ELFDATA"))
+	field("Version", "%d", hdr.Ident[elf.EI_VERSION])
+	field("OS/ABI", "%s", trim(hdr.OSABI, "ELFOSABI_"))
+	field("ABI Version", "%d", hdr.ABIVersion)
+	field("Type", "%s", trim(hdr.Type, "ET_"))
+	field("Machine", "%s", trim(hdr.Machine, "EM_"))
+	field("Version", "%#x", uint32(hdr.Version))
+	field("Entry point address", "%#x", hdr.Entry)
+	field("Start of program headers", "%d (bytes into file)", hdr.Phoff)
+	field("Start of section headers", "%d (bytes into file)", hdr.Shoff)
+	field("Flags", "%#x", hdr.Flags)
+	field("Size of this header", "%d (bytes)", hdr.Ehsize)
+	field("Size of program headers", "%d (bytes)", hdr.Phentsize)
+	field("Number of program headers", "%d", hdr.Phnum)
+	field("Size of section headers", "%d (bytes)", hdr.Shentsize)
+	field("Number of section headers", "%d", hdr.Shnum)
+	field("Section header string table index", "%d", hdr.Shstrndx)
+}
+
+var sectionFlags = []struct {
+	flag elf.SectionFlag
+	key  byte
+}{
+	{elf.SHF_WRITE, 'W'},
+	{elf.SHF_ALLOC, 'A'},
+	{elf.SHF_EXECINSTR, 'X'},
+	{elf.SHF_MERGE, 'M'},
+	{elf.SHF_STRINGS, 'S'},
+	{elf.SHF_INFO_LINK, 'I'},
+	{elf.SHF_LINK_ORDER, 'L'},
+	{elf.SHF_OS_NONCONFORMING, 'O'},
+	{elf.SHF_GROUP, 'G'},
+	{elf.SHF_TLS, 'T'},
+	{elf.SHF_COMPRESSED, 'C'},
+	{0x80000000, 'E'},
+}
+
+func flagKeys(flags elf.SectionFlag) string {
+	var keys []byte
+	for _, f := range sectionFlags {
+		if flags&f.flag != 0 {
+			keys = append(keys, f.key)
+		}
+	}
+	return string(keys)
+}
+
+func addrWidth(hdr *bfd.ElfHeader) int {
+	if hdr.Class == elf.ELFCLASS32 {
+		return 8
+	}
+	return 16
+}
+
+func printSectionHeaders(hdr *bfd.ElfHeader, sections []*bfd.ElfSectionHeader) {
+	if len(sections) == 0 {
+		fmt.Printf("\nThere are no sections in this file.\n")
+		return
+	}
+	fmt.Printf("\nThere are %d section headers, starting at offset %#x:\n\n", len(sections), hdr.Shoff)
+	fmt.Println("Section Headers:")
+
+	w := addrWidth(hdr)
+	fmt.Printf("  [Nr] %-17s %-15s %-*s %-6s %-6s ES Flg Lk Inf Al\n", "Name", "Type", w, "Address", "Off", "Size")
+	for _, s := range sections {
+		fmt.Printf("  [%2d] %-17s %-15s %0*x %06x %06x %02x %3s %2d %3d %2d\n",
+			s.Index, s.Name, trim(s.Type, "SHT_"), w, s.Addr, s.Offset, s.Size,
+			s.Entsize, flagKeys(s.Flags), s.Link, s.Info, s.Addralign)
+	}
+	fmt.Println("Key to Flags:")
+	fmt.Println("  W (write), A (alloc), X (execute), M (merge), S (strings), I (info),")
+	fmt.Println("  L (link order), O (extra OS processing required), G (group), T (TLS),")
+	fmt.Println("  C (compressed), E (exclude)")
+}
+
+func progFlags(flags elf.ProgFlag) string {
+	keys := []byte("   ")
+	if flags&elf.PF_R != 0 {
+		keys[0] = 'R'
+	}
+	if flags&elf.PF_W != 0 {
+		keys[1] = 'W'
+	}
+	if flags&elf.PF_X != 0 {
+		keys[2] = 'E'
+	}
+	return string(keys)
+}
+
+func printProgramHeaders(abfd *bfd.File, hdr *bfd.ElfHeader, phdrs []bfd.ElfProgramHeader, sections []*bfd.ElfSectionHeader) {
+	if len(phdrs) == 0 {
+		fmt.Printf("\nThere are no program headers in this file.\n")
+		return
+	}
+	fmt.Printf("\nElf file type is %s\n", trim(hdr.Type, "ET_"))
+	fmt.Printf("Entry point %#x\n", hdr.Entry)
+	fmt.Printf("There are %d program headers, starting at offset %d\n\n", len(phdrs), hdr.Phoff)
+	fmt.Println("Program Headers:")
+
+	w := addrWidth(hdr)
+	fmt.Printf("  %-14s %-8s %-*s %-*s %-8s %-8s Flg Align\n", "Type", "Offset", w+2, "VirtAddr", w+2, "PhysAddr", "FileSiz", "MemSiz")
+	for _, p := range phdrs {
+		fmt.Printf("  %-14s 0x%06x 0x%0*x 0x%0*x 0x%06x 0x%06x %s %#x\n",
+			trim(p.Type, "PT_"), p.Offset, w, p.Vaddr, w, p.Paddr, p.Filesz, p.Memsz, progFlags(p.Flags), p.Align)
+		if p.Type == elf.PT_INTERP {
+			buf := make([]byte, p.Filesz)
+			if _, err := abfd.ReadAt(buf, int64(p.Offset)); err == nil {
+				buf = bytes.TrimRight(buf, "\x00")
+				fmt.Printf("      [Requesting program interpreter: %s]\n", buf)
+			}
+		}
+	}
+
+	fmt.Printf("\n Section to Segment mapping:\n")
+	fmt.Printf("  Segment Sections...\n")
+	for i, list := range bfd.ElfSegmentMap(phdrs, sections) {
+		fmt.Printf("   %02d     ", i)
+		for _, s := range list {
+			fmt.Printf("%s ", s.Name)
+		}
+		fmt.Println()
+	}
+}
+
+func printDynamic(hdr *bfd.ElfHeader, dyn *bfd.ElfDynamic) {
+	if dyn == nil {
+		fmt.Printf("\nThere is no dynamic section in this file.\n")
+		return
+	}
+	fmt.Printf("\nDynamic section contains %d entries:\n", len(dyn.Entries))
+
+	w := addrWidth(hdr)
+	fmt.Printf("  %-*s %-20s %s\n", w+2, "Tag", "Type", "Name/Value")
+	needed := dyn.Needed
+	for _, d := range dyn.Entries {
+		fmt.Printf(" 0x%0*x %-20s ", w, uint64(d.Tag), "("+trim(d.Tag, "DT_")+")")
+		switch d.Tag {
+		case elf.DT_NEEDED:
+			if len(needed) > 0 {
+				fmt.Printf("Shared library: [%s]\n", needed[0])
+				needed = needed[1:]
+			} else {
+				fmt.Printf("%#x\n", d.Val)
+			}
+		case elf.DT_SONAME:
+			fmt.Printf("Library soname: [%s]\n", dyn.Soname)
+		case elf.DT_RPATH:
+			fmt.Printf("Library rpath: [%s]\n", strings.Join(dyn.Rpath, ":"))
+		case elf.DT_RUNPATH:
+			fmt.Printf("Library runpath: [%s]\n", strings.Join(dyn.Runpath, ":"))
+		case elf.DT_FLAGS:
+			fmt.Println(dynFlags(uint64(dyn.Flags), "DF_", func(f uint64) fmt.Stringer { return elf.DynFlag(f) }))
+		case elf.DT_FLAGS_1:
+			fmt.Println("Flags:", dynFlags(uint64(dyn.Flags1), "DF_1_", func(f uint64) fmt.Stringer { return elf.DynFlag1(f) }))
+		case elf.DT_PLTRELSZ, elf.DT_RELASZ, elf.DT_RELAENT, elf.DT_STRSZ, elf.DT_SYMENT,
+			elf.DT_RELSZ, elf.DT_RELENT, elf.DT_INIT_ARRAYSZ, elf.DT_FINI_ARRAYSZ, elf.DT_PREINIT_ARRAYSZ:
+			fmt.Printf("%d (bytes)\n", d.Val)
+		case elf.DT_RELACOUNT, elf.DT_RELCOUNT, elf.DT_VERNEEDNUM, elf.DT_VERDEFNUM:
+			fmt.Printf("%d\n", d.Val)
+		default:
+			fmt.Printf("%#x\n", d.Val)
+		}
+	}
+}
+
+// dynFlags names each bit set in flags, leaving unknown bits in hex
+func dynFlags(flags uint64, prefix string, name func(uint64) fmt.Stringer) string {
+	var list []string
+	for bit := uint64(1); bit != 0 && flags != 0; bit <<= 1 {
+		if flags&bit == 0 {
+			continue
+		}
+		flags &^= bit
+		list = append(list, trim(name(bit), prefix))
+	}
+	return strings.Join(list, " ")
+}
+
+func sectionIndex(index elf.SectionIndex) string {
+	switch index {
+	case elf.SHN_UNDEF:
+		return "UND"
+	case elf.SHN_ABS:
+		return "ABS"
+	case elf.SHN_COMMON:
+		return "COM"
+	}
+	return fmt.Sprint(uint32(index))
+}
+
+func printSymbols(hdr *bfd.ElfHeader, syms []bfd.ElfSymbol, dynamic bool) {
+	if syms == nil {
+		return
+	}
+	name := ".symtab"
+	if dynamic {
+		name = ".dynsym"
+	}
+	// the null symbol is not in the list but is still counted and numbered
+	fmt.Printf("\nSymbol table '%s' contains %d entries:\n", name, len(syms)+1)
+
+	w := addrWidth(hdr)
+	fmt.Printf("   Num: %-*s  Size Type    Bind   Vis      Ndx Name\n", w, "Value")
+	fmt.Printf("     0: %0*x     0 NOTYPE  LOCAL  DEFAULT  UND \n", w, 0)
+	for i, s := range syms {
+		fmt.Printf("%6d: %0*x %5d %-7s %-6s %-8s %3s %s\n", i+1, w, s.Value, s.Size,
+			trim(s.Type, "STT_"), trim(s.Bind, "STB_"), trim(s.Visibility, "STV_"), sectionIndex(s.Section), s.Name)
+	}
+}
+
+func printVersions(ver *bfd.ElfVersions) {
+	if ver == nil {
+		fmt.Printf("\nNo version information found in this file.\n")
+		return
+	}
+
+	if len(ver.Symbols) > 0 {
+		fmt.Printf("\nVersion symbols contains %d entries:\n", len(ver.Symbols))
+		for i, sv := range ver.Symbols {
+			if sv.Index < 2 {
+				continue
+			}
+			name := sv.Version
+			if sv.Library != "" {
+				name += " from " + sv.Library
+			}
+			hidden := ' '
+			if sv.Hidden {
+				hidden = 'h'
+			}
+			fmt.Printf("  %4d: %4d%c %-30s (%s)\n", i, sv.Index, hidden, sv.Name, name)
+		}
+	}
+
+	if len(ver.Defs) > 0 {
+		fmt.Printf("\nVersion definition section contains %d entries:\n", len(ver.Defs))
+		for _, d := range ver.Defs {
+			flags := "none"
+			if d.Flags&bfd.ElfVerFlagBase != 0 {
+				flags = "BASE"
+			}
+			fmt.Printf("  Index: %d  Flags: %s  Hash: %#x  Name: %s\n", d.Index, flags, d.Hash, d.Name)
+			for _, p := range d.Parents {
+				fmt.Printf("    Parent: %s\n", p)
+			}
+		}
+	}
+
+	if len(ver.Needs) > 0 {
+		fmt.Printf("\nVersion needs section contains %d entries:\n", len(ver.Needs))
+		for _, n := range ver.Needs {
+			fmt.Printf("  File: %s  Cnt: %d\n", n.File, len(n.Versions))
+			for _, a := range n.Versions {
+				flags := "none"
+				if a.Flags&bfd.ElfVerFlagWeak != 0 {
+					flags = "WEAK"
+				}
+				fmt.Printf("    Name: %s  Flags: %s  Version: %d\n", a.Name, flags, a.Index)
+			}
+		}
+	}
+}
+
+func printNotes(notes []bfd.ElfNote) {
+	section := "\x00"
+	for i := range notes {
+		n := &notes[i]
+		if n.Section != section {
+			section = n.Section
+			if section == "" {
+				fmt.Printf("\nDisplaying notes found in segments:\n")
+			} else {
+				fmt.Printf("\nDisplaying notes found in: %s\n", section)
+			}
+			fmt.Printf("  %-20s %-10s %s\n", "Owner", "Data size", "Description")
+		}
+		fmt.Printf("  %-20s %#08x %#x\n", n.Name, len(n.Desc), n.Type)
+		printNote(n)
+	}
+}
+
+func printNote(n *bfd.ElfNote) {
+	if id, ok := n.BuildID(); ok {
+		fmt.Printf("    Build ID: %s\n", id)
+	}
+	if tag, ok := n.ABITag(); ok {
+		fmt.Printf("    OS: %s, ABI: %d.%d.%d\n", tag.OS, tag.Major, tag.Minor, tag.Patch)
+	}
+	if props, err := n.Properties(); err == nil {
+		for _, p := range props {
+			fmt.Printf("    Property %#x: % x\n", p.Type, p.Data)
+		}
+		var features []string
+		f := n.Features()
+		for _, x := range []struct {
+			set  bool
+			name string
+		}{{f.IBT, "IBT"}, {f.SHSTK, "SHSTK"}, {f.BTI, "BTI"}, {f.PAC, "PAC"}} {
+			if x.set {
+				features = append(features, x.name)
+			}
+		}
+		if len(features) > 0 {
+			fmt.Printf("    Features: %s\n", strings.Join(features, ", "))
+		}
+	}
+	if probe, ok := n.Stapsdt(); ok {
+		fmt.Printf("    Provider: %s\n", probe.Provider)
+		fmt.Printf("    Name: %s\n", probe.Name)
+		fmt.Printf("    Location: %#x, Base: %#x, Semaphore: %#x\n", probe.PC, probe.Base, probe.Semaphore)
+		fmt.Printf("    Arguments: %s\n", probe.Args)
+	}
+	if pkg, err := n.Package(); err == nil {
+		fmt.Printf("    Packaging Metadata: %s\n", pkg.Raw)
+	}
+}
@@ -0,0 +1,265 @@
+// ported from gnu size
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+var (
+	format     = flag.String("format", "berkeley", "use the output format (berkeley, sysv)")
+	sysvFormat = flag.Bool("A", false, "same as -format sysv")
+	bsdFormat  = flag.Bool("B", false, "same as -format berkeley")
+	radix      = flag.Int("radix", 10, "display numbers in octal, decimal or hex (8, 10, 16)")
+	octal      = flag.Bool("o", false, "same as -radix 8")
+	decimal    = flag.Bool("d", false, "same as -radix 10")
+	hex        = flag.Bool("x", false, "same as -radix 16")
+	totals     = flag.Bool("t", false, "display the total sizes (berkeley only)")
+	commonSize = flag.Bool("common", false, "display total size for *COM* syms")
+	target     = flag.String("target", "", "set the binary file format")
+
+	berkeley                       bool
+	textTotal, dataTotal, bssTotal int64
+	filesSeen                      bool
+	status                         int
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("size: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	switch {
+	case *sysvFormat:
+		*format = "sysv"
+	case *bsdFormat:
+		*format = "berkeley"
+	}
+	switch *format {
+	case "berkeley", "bsd":
+		berkeley = true
+	case "sysv", "SysV":
+	default:
+		log.Fatalf("invalid output format %q", *format)
+	}
+	switch {
+	case *octal:
+		*radix = 8
+	case *decimal:
+		*radix = 10
+	case *hex:
+		*radix = 16
+	}
+	switch *radix {
+	case 8, 10, 16:
+	default:
+		log.Fatalf("invalid radix %d", *radix)
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"a.out"}
+	}
+	for _, name := range args {
+		displayFile(name)
+	}
+
+	if berkeley && *totals && filesSeen {
+		printBerkeleyLine(textTotal, dataTotal, bssTotal, "(TOTALS)")
+	}
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: [-A|-B] [options] [file ...]")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ek(err error) bool {
+	if err != nil {
+		log.Print(err)
+		status = 1
+		return true
+	}
+	return false
+}
+
+func displayFile(name string) {
+	abfd, err := bfd.Openr(name, *target)
+	if ek(err) {
+		return
+	}
+	defer bfd.Close(abfd)
+
+	if bfd.CheckFormat(abfd, bfd.Archive) == nil {
+		var member *bfd.File
+		for {
+			member, err = bfd.OpenrNextArchivedFile(abfd, member)
+			if err == bfd.ErrNoMoreArchivedFiles {
+				break
+			}
+			if ek(err) {
+				return
+			}
+			displayBfd(member, abfd)
+		}
+		return
+	}
+	displayBfd(abfd, nil)
+}
+
+func displayBfd(abfd, archive *bfd.File) {
+	if _, err := bfd.CheckFormatMatches(abfd, bfd.Object); err != nil {
+		if bfd.CheckFormat(abfd, bfd.Core) != nil {
+			name := abfd.Filename()
+			if archive != nil {
+				name = archive.Filename() + "(" + name + ")"
+			}
+			ek(fmt.Errorf("%s: %v", name, err))
+			return
+		}
+	}
+
+	if berkeley {
+		printBerkeley(abfd, archive)
+	} else {
+		printSysV(abfd, archive)
+	}
+	filesSeen = true
+}
+
+// column formats n in the chosen radix, padded to width
+func column(n int64, width int) string {
+	switch *radix {
+	case 8:
+		return fmt.Sprintf("%#*o", width, n)
+	case 16:
+		return fmt.Sprintf("%#*x", width, n)
+	}
+	return fmt.Sprintf("%*d", width, n)
+}
+
+// commonSymbolsSize adds up the sizes of common symbols, which take up bss
+// in the final link but no section space in an object
+func commonSymbolsSize(abfd *bfd.File) int64 {
+	if abfd.Flags()&bfd.HAS_SYMS == 0 {
+		return 0
+	}
+	storage := bfd.GetSymtabUpperBound(abfd)
+	if storage <= 0 {
+		return 0
+	}
+	table := bfd.AllocSymbolTable(storage)
+	defer table.Free()
+	count, err := bfd.CanonicalizeSymtab(abfd, table)
+	if err != nil {
+		return 0
+	}
+
+	var size int64
+	for i := int64(0); i < count; i++ {
+		sym := table.Symbol(i)
+		if sym != nil && bfd.IsComSection(sym.Section()) {
+			size += int64(sym.Value())
+		}
+	}
+	return size
+}
+
+func printBerkeley(abfd, archive *bfd.File) {
+	var text, data, bss int64
+	for s := abfd.Sections(); s != nil; s = s.Next() {
+		flags := s.Flags()
+		if flags&bfd.SEC_ALLOC == 0 {
+			continue
+		}
+		size := int64(bfd.GetSectionSize(s))
+		switch {
+		case flags&bfd.SEC_CODE != 0 || flags&bfd.SEC_READONLY != 0:
+			text += size
+		case flags&bfd.SEC_HAS_CONTENTS != 0:
+			data += size
+		default:
+			bss += size
+		}
+	}
+	if *commonSize {
+		bss += commonSymbolsSize(abfd)
+	}
+
+	textTotal += text
+	dataTotal += data
+	bssTotal += bss
+
+	name := abfd.Filename()
+	if archive != nil {
+		name += " (ex " + archive.Filename() + ")"
+	}
+	printBerkeleyLine(text, data, bss, name)
+}
+
+func printBerkeleyLine(text, data, bss int64, name string) {
+	if !filesSeen {
+		if *radix == 8 {
+			fmt.Println("   text\t   data\t    bss\t    oct\t    hex\tfilename")
+		} else {
+			fmt.Println("   text\t   data\t    bss\t    dec\t    hex\tfilename")
+		}
+	}
+
+	total := text + data + bss
+	fmt.Printf("%s\t%s\t%s\t", column(text, 7), column(data, 7), column(bss, 7))
+	if *radix == 8 {
+		fmt.Printf("%7o\t", total)
+	} else {
+		fmt.Printf("%7d\t", total)
+	}
+	fmt.Printf("%7x\t%s\n", total, name)
+}
+
+func printSysV(abfd, archive *bfd.File) {
+	nameWidth := len("section")
+	sizeWidth := len("size")
+	addrWidth := len("addr")
+	var total int64
+
+	var sections []*bfd.Section
+	for s := abfd.Sections(); s != nil; s = s.Next() {
+		sections = append(sections, s)
+		size := int64(bfd.GetSectionSize(s))
+		total += size
+		if n := len(s.Name()); n > nameWidth {
+			nameWidth = n
+		}
+		if n := len(column(size, 0)); n > sizeWidth {
+			sizeWidth = n
+		}
+		if n := len(column(int64(s.VMA()), 0)); n > addrWidth {
+			addrWidth = n
+		}
+	}
+	if *commonSize {
+		total += commonSymbolsSize(abfd)
+	}
+	if n := len(column(total, 0)); n > sizeWidth {
+		sizeWidth = n
+	}
+
+	if archive != nil {
+		fmt.Printf("%s   (ex %s):\n", abfd.Filename(), archive.Filename())
+	} else {
+		fmt.Printf("%s  :\n", abfd.Filename())
+	}
+	fmt.Printf("%-*s   %*s   %*s\n", nameWidth, "section", sizeWidth, "size", addrWidth, "addr")
+	for _, s := range sections {
+		fmt.Printf("%-*s   %s   %s\n", nameWidth, s.Name(),
+			column(int64(bfd.GetSectionSize(s)), sizeWidth), column(int64(s.VMA()), addrWidth))
+	}
+	fmt.Printf("%-*s   %s\n\n\n", nameWidth, "Total", column(total, sizeWidth))
+}
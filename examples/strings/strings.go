@@ -0,0 +1,299 @@
+// ported from gnu strings
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+var (
+	scanAll      = flag.Bool("a", true, "scan the entire file")
+	dataOnly     = flag.Bool("d", false, "only scan the initialized, loaded data sections")
+	printName    = flag.Bool("f", false, "print the name of the file before each string")
+	minLen       = flag.Int("n", 4, "print sequences of at least n characters")
+	radix        = flag.String("t", "", "print the location of the string in base 8, 10 or 16 (o, d, x)")
+	octal        = flag.Bool("o", false, "same as -t o")
+	whitespace   = flag.Bool("w", false, "include all whitespace as valid string characters")
+	encoding     = flag.String("e", "s", "character size and endianness: s = 7-bit, S = 8-bit, {b,l} = 16-bit, {B,L} = 32-bit")
+	target       = flag.String("T", "", "specify the binary file format")
+	useVMA       = flag.Bool("vma", false, "print virtual addresses instead of file offsets where known")
+	showLocation = flag.Bool("m", false, "print the section and symbol holding each string")
+	separator    = flag.String("s", "\n", "string to print after each string")
+
+	status int
+)
+
+// location maps file offsets of an object back to its sections and symbols
+type location struct {
+	abfd    *bfd.File
+	syms    *bfd.SymbolTable
+	sorted  map[*bfd.Section][]*bfd.Symbol
+	section *bfd.Section
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("strings: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *minLen < 1 {
+		log.Fatalf("invalid minimum string length %d", *minLen)
+	}
+	if *octal {
+		*radix = "o"
+	}
+	switch *radix {
+	case "", "o", "d", "x":
+	default:
+		log.Fatalf("invalid radix %q", *radix)
+	}
+	if _, _, err := charSize(*encoding); err != nil {
+		log.Fatal(err)
+	}
+	if *dataOnly {
+		*scanAll = false
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		printStrings("{standard input}", bufio.NewReader(os.Stdin), 0, nil)
+	}
+	for _, name := range args {
+		scanFile(name)
+	}
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: [options] [file ...]")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func ek(err error) bool {
+	if err != nil {
+		log.Print(err)
+		status = 1
+		return true
+	}
+	return false
+}
+
+func charSize(enc string) (size int, big bool, err error) {
+	switch enc {
+	case "s", "S":
+		return 1, false, nil
+	case "b":
+		return 2, true, nil
+	case "l":
+		return 2, false, nil
+	case "B":
+		return 4, true, nil
+	case "L":
+		return 4, false, nil
+	}
+	return 0, false, fmt.Errorf("invalid encoding %q", enc)
+}
+
+func scanFile(name string) {
+	loc := openLocation(name)
+	if loc != nil {
+		defer loc.close()
+	}
+
+	if !*scanAll {
+		if loc == nil {
+			ek(fmt.Errorf("%s: file format not recognized", name))
+			return
+		}
+		for s := loc.abfd.Sections(); s != nil; s = s.Next() {
+			const want = bfd.SEC_ALLOC | bfd.SEC_LOAD | bfd.SEC_HAS_CONTENTS
+			if s.Flags()&want != want || s.Flags()&bfd.SEC_CODE != 0 {
+				continue
+			}
+			buf := make([]byte, bfd.GetSectionSize(s))
+			if ek(bfd.GetSectionContents(loc.abfd, s, buf, 0)) {
+				continue
+			}
+			loc.section = s
+			printStrings(name, bufio.NewReader(bytes.NewReader(buf)), s.Filepos(), loc)
+		}
+		return
+	}
+
+	f, err := os.Open(name)
+	if ek(err) {
+		return
+	}
+	defer f.Close()
+	printStrings(name, bufio.NewReader(f), 0, loc)
+}
+
+// openLocation opens name as an object for mapping strings back to where
+// they live; anything bfd does not understand is still scanned, just
+// without the mapping. Sections are left compressed, so that their sizes
+// match the bytes in the file.
+func openLocation(name string) *location {
+	abfd, err := bfd.Openr(name, *target)
+	if err != nil {
+		return nil
+	}
+	if bfd.CheckFormat(abfd, bfd.Object) != nil {
+		bfd.Close(abfd)
+		return nil
+	}
+
+	loc := &location{abfd: abfd, sorted: make(map[*bfd.Section][]*bfd.Symbol)}
+	if abfd.Flags()&bfd.HAS_SYMS != 0 {
+		if storage := bfd.GetSymtabUpperBound(abfd); storage > 0 {
+			loc.syms = bfd.AllocSymbolTable(storage)
+			if _, err := bfd.CanonicalizeSymtab(abfd, loc.syms); err != nil {
+				loc.syms.Free()
+				loc.syms = nil
+			}
+		}
+	}
+	if loc.syms != nil {
+		for i := int64(0); i < loc.syms.Size(); i++ {
+			sym := loc.syms.Symbol(i)
+			if sym == nil || sym.Flags()&(bfd.BSF_SECTION_SYM|bfd.BSF_FILE|bfd.BSF_DEBUGGING) != 0 {
+				continue
+			}
+			sec := sym.Section()
+			loc.sorted[sec] = append(loc.sorted[sec], sym)
+		}
+		for _, syms := range loc.sorted {
+			sort.SliceStable(syms, func(i, j int) bool { return syms[i].Address() < syms[j].Address() })
+		}
+	}
+	return loc
+}
+
+func (l *location) close() {
+	if l.syms != nil {
+		l.syms.Free()
+	}
+	bfd.Close(l.abfd)
+}
+
+// find returns the section holding the byte at file offset off and its
+// address, or nil if it is not part of any section contents
+func (l *location) find(off int64) (*bfd.Section, bfd.VMA) {
+	if l.section != nil {
+		return l.section, l.section.VMA() + bfd.VMA(off-l.section.Filepos())
+	}
+	for s := l.abfd.Sections(); s != nil; s = s.Next() {
+		if s.Flags()&bfd.SEC_HAS_CONTENTS == 0 {
+			continue
+		}
+		if off >= s.Filepos() && off < s.Filepos()+s.Size() {
+			return s, s.VMA() + bfd.VMA(off-s.Filepos())
+		}
+	}
+	return nil, 0
+}
+
+// describe names the place of vma in sec as section or section:symbol+offset
+func (l *location) describe(sec *bfd.Section, vma bfd.VMA) string {
+	syms := l.sorted[sec]
+	i := sort.Search(len(syms), func(i int) bool { return syms[i].Address() > vma })
+	if i == 0 {
+		return sec.Name()
+	}
+	sym := syms[i-1]
+	if off := vma - sym.Address(); off != 0 {
+		return fmt.Sprintf("%s:%s+%#x", sec.Name(), sym.Name(), uint64(off))
+	}
+	return sec.Name() + ":" + sym.Name()
+}
+
+func isGraphic(c rune) bool {
+	if c > 255 {
+		return false
+	}
+	if c == '\t' || c >= ' ' && c < 0x7f {
+		return true
+	}
+	if *whitespace && (c == '\n' || c == '\r' || c == '\v' || c == '\f') {
+		return true
+	}
+	return *encoding == "S" && c > 127
+}
+
+// printStrings prints every run of at least -n graphic characters in r; off
+// is the file offset of the first byte
+func printStrings(name string, r *bufio.Reader, off int64, loc *location) {
+	size, big, _ := charSize(*encoding)
+	char := make([]byte, size)
+
+	var str []byte
+	var start int64
+	for pos := off; ; pos += int64(size) {
+		n, _ := io.ReadFull(r, char)
+		c := rune(-1)
+		if n == size {
+			c = 0
+			for i, b := range char {
+				if big {
+					c = c<<8 | rune(b)
+				} else {
+					c |= rune(b) << (8 * uint(i))
+				}
+			}
+		}
+		if c >= 0 && isGraphic(c) {
+			if len(str) == 0 {
+				start = pos
+			}
+			str = append(str, byte(c))
+			continue
+		}
+
+		if len(str) >= *minLen {
+			printString(name, start, string(str), loc)
+		}
+		str = str[:0]
+		if n < size {
+			return
+		}
+	}
+}
+
+func printString(name string, off int64, str string, loc *location) {
+	if *printName {
+		fmt.Printf("%s: ", name)
+	}
+
+	var sec *bfd.Section
+	var vma bfd.VMA
+	if loc != nil {
+		sec, vma = loc.find(off)
+	}
+	where := uint64(off)
+	if *useVMA && sec != nil {
+		where = uint64(vma)
+	}
+	switch *radix {
+	case "o":
+		fmt.Printf("%7o ", where)
+	case "d":
+		fmt.Printf("%7d ", where)
+	case "x":
+		fmt.Printf("%7x ", where)
+	}
+
+	fmt.Print(str)
+	if *showLocation && sec != nil {
+		fmt.Printf(" [%s]", loc.describe(sec, vma))
+	}
+	fmt.Print(*separator)
+}
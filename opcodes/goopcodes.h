@@ -0,0 +1,5 @@
+void initDisassembleInfo(disassemble_info *info);
+int disassembleOne(disassembler_ftype fn, bfd_vma pc, disassemble_info *info);
+void goFprintf(void *stream, char *str);
+void setPrintAddressFunc(disassemble_info *info, int custom);
+void goPrintAddress(bfd_vma addr, disassemble_info *info);
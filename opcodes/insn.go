@@ -0,0 +1,113 @@
+package opcodes
+
+/*
+#include <dis-asm.h>
+*/
+import "C"
+
+import (
+	"strings"
+)
+
+// InsnType is the kind of an instruction. libopcodes does not tell loads
+// from stores, both are data references.
+type InsnType C.enum_dis_insn_type
+
+const (
+	InsnNonInsn    InsnType = C.dis_noninsn    // not a valid instruction
+	InsnNonBranch  InsnType = C.dis_nonbranch  // not a branch or data reference
+	InsnBranch     InsnType = C.dis_branch     // unconditional branch
+	InsnCondBranch InsnType = C.dis_condbranch // conditional branch
+	InsnJSR        InsnType = C.dis_jsr        // jump to subroutine
+	InsnCondJSR    InsnType = C.dis_condjsr    // conditional jump to subroutine
+	InsnDref       InsnType = C.dis_dref       // load or store, Target is the address
+	InsnDref2      InsnType = C.dis_dref2      // two data references, in Target and Target2
+)
+
+func (t InsnType) String() string {
+	switch t {
+	case InsnNonInsn:
+		return "noninsn"
+	case InsnNonBranch:
+		return "nonbranch"
+	case InsnBranch:
+		return "branch"
+	case InsnCondBranch:
+		return "condbranch"
+	case InsnJSR:
+		return "jsr"
+	case InsnCondJSR:
+		return "condjsr"
+	case InsnDref:
+		return "dref"
+	case InsnDref2:
+		return "dref2"
+	}
+	return "unknown"
+}
+
+// IsBranch reports whether the instruction can transfer control.
+func (t InsnType) IsBranch() bool {
+	switch t {
+	case InsnBranch, InsnCondBranch, InsnJSR, InsnCondJSR:
+		return true
+	}
+	return false
+}
+
+// prefixes printed in front of the x86 mnemonic
+var prefixes = map[string]bool{
+	"lock": true, "rep": true, "repz": true, "repnz": true, "repe": true, "repne": true,
+	"bnd": true, "notrack": true, "xacquire": true, "xrelease": true,
+	"data16": true, "data32": true, "addr16": true, "addr32": true,
+	"cs": true, "ds": true, "es": true, "fs": true, "gs": true, "ss": true,
+}
+
+// splitText breaks disassembler output into its mnemonic, operands and
+// trailing comment, such as the "# 0x4010 <foo>" x86 adds for rip
+// relative operands.
+func splitText(text string) (mnemonic string, operands []string, comment string) {
+	text = strings.TrimSpace(text)
+	for _, marker := range []string{" # ", "\t# ", " ; ", "\t; ", " // ", "\t// "} {
+		if i := strings.Index(text, marker); i >= 0 {
+			comment = strings.TrimSpace(text[i+len(marker):])
+			text = strings.TrimSpace(text[:i])
+		}
+	}
+
+	var words []string
+	for text != "" {
+		i := strings.IndexAny(text, " \t")
+		if i < 0 {
+			words, text = append(words, text), ""
+			break
+		}
+		word := text[:i]
+		words = append(words, word)
+		text = strings.TrimLeft(text[i:], " \t")
+		if !prefixes[word] {
+			break
+		}
+	}
+	mnemonic = strings.Join(words, " ")
+
+	depth := 0
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				operands = append(operands, strings.TrimSpace(text[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if op := strings.TrimSpace(text[start:]); op != "" {
+		operands = append(operands, op)
+	}
+	return
+}
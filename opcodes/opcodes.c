@@ -0,0 +1,67 @@
+#include <dis-asm.h>
+#include <stdarg.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include "goopcodes.h"
+
+static int
+fprintfFunc(void *stream, const char *format, ...)
+{
+	va_list ap;
+	char buf[128], *str;
+	int n;
+
+	va_start(ap, format);
+	n = vsnprintf(buf, sizeof(buf), format, ap);
+	va_end(ap);
+	if (n < 0)
+		return n;
+
+	str = buf;
+	if (n >= (int)sizeof(buf)) {
+		str = malloc(n + 1);
+		if (str == NULL)
+			return -1;
+		va_start(ap, format);
+		vsnprintf(str, n + 1, format, ap);
+		va_end(ap);
+	}
+	goFprintf(stream, str);
+	if (str != buf)
+		free(str);
+	return n;
+}
+
+void
+initDisassembleInfo(disassemble_info *info)
+{
+	init_disassemble_info(info, info, (fprintf_ftype)fprintfFunc);
+}
+
+int
+disassembleOne(disassembler_ftype fn, bfd_vma pc, disassemble_info *info)
+{
+	/* not every disassembler fills these in, clear what the last one left */
+	info->insn_info_valid = 0;
+	info->branch_delay_insns = 0;
+	info->data_size = 0;
+	info->insn_type = dis_noninsn;
+	info->target = 0;
+	info->target2 = 0;
+	return fn(pc, info);
+}
+
+static void
+printAddressFunc(bfd_vma addr, disassemble_info *info)
+{
+	goPrintAddress(addr, info);
+}
+
+void
+setPrintAddressFunc(disassemble_info *info, int custom)
+{
+	if (custom)
+		info->print_address_func = printAddressFunc;
+	else
+		info->print_address_func = generic_print_address;
+}
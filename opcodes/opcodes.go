@@ -0,0 +1,251 @@
+package opcodes
+
+/*
+#include <dis-asm.h>
+#include <stdlib.h>
+#include <string.h>
+#include "goopcodes.h"
+
+#cgo LDFLAGS: -lopcodes -lbfd
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/qeedquan/go-binutils/bfd"
+)
+
+// Disassembler options understood by the x86 disassembler.
+const (
+	IntelSyntax = "intel"
+	ATTSyntax   = "att"
+)
+
+type Instruction struct {
+	Address bfd.VMA
+	Bytes   []byte
+	Text    string
+
+	// Text split up; the mnemonic keeps any prefixes such as lock or rep
+	Mnemonic string
+	Operands []string
+	Comment  string
+
+	// What libopcodes tells about the instruction when InfoValid is set,
+	// which only some architectures (mips, powerpc, sparc, ...) do
+	InfoValid   bool
+	Type        InsnType
+	BranchDelay int
+	DataSize    int
+	Target      bfd.VMA
+	Target2     bfd.VMA
+}
+
+// SymbolFunc names the symbol an address falls in, returning the offset of
+// the address from the start of the symbol, or false if there is none.
+type SymbolFunc func(addr bfd.VMA) (name string, offset bfd.VMA, ok bool)
+
+type Disassembler struct {
+	abfd    *bfd.File
+	info    *C.disassemble_info
+	fn      C.disassembler_ftype
+	options *C.char
+	symbol  SymbolFunc
+	text    strings.Builder
+}
+
+type disassemblers struct {
+	sync.Mutex
+	m map[*C.disassemble_info]*Disassembler
+}
+
+var (
+	dm = disassemblers{m: make(map[*C.disassemble_info]*Disassembler)}
+)
+
+func lookupDisassembler(info *C.disassemble_info) *Disassembler {
+	dm.Lock()
+	defer dm.Unlock()
+	return dm.m[info]
+}
+
+//export goFprintf
+func goFprintf(stream unsafe.Pointer, str *C.char) {
+	d := lookupDisassembler((*C.disassemble_info)(stream))
+	d.text.WriteString(C.GoString(str))
+}
+
+//export goPrintAddress
+func goPrintAddress(addr C.bfd_vma, info *C.disassemble_info) {
+	d := lookupDisassembler(info)
+	fmt.Fprintf(&d.text, "%#x", uint64(addr))
+	name, off, ok := d.symbol(bfd.VMA(addr))
+	switch {
+	case !ok:
+	case off == 0:
+		fmt.Fprintf(&d.text, " <%s>", name)
+	default:
+		fmt.Fprintf(&d.text, " <%s+%#x>", name, uint64(off))
+	}
+}
+
+func cbool(b bool) C.bfd_boolean {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// New returns a disassembler for the architecture and machine of abfd.
+// The options are handed to libopcodes as is, comma separated, such as
+// IntelSyntax on x86; objdump -M lists what each architecture takes.
+func New(abfd *bfd.File, options string) (*Disassembler, error) {
+	arch := bfd.GetArch(abfd)
+	mach := bfd.GetMach(abfd)
+	d, err := newDisassembler(arch, mach, abfd.Xvec().ByteOrder(), abfd, options)
+	if err != nil {
+		return nil, err
+	}
+	d.info.flavour = C.enum_bfd_flavour(bfd.GetFlavor(abfd))
+	return d, nil
+}
+
+// NewRaw returns a disassembler for code that is not part of any file.
+// The architecture is named as bfd_scan_arch takes it, e.g. "i386:x86-64"
+// or "arm"; bfd.ArchList has the known ones.
+func NewRaw(arch string, endian bfd.Endian, options string) (*Disassembler, error) {
+	ai := bfd.ScanArch(arch)
+	if ai == nil {
+		return nil, fmt.Errorf("unknown architecture %q", arch)
+	}
+	return newDisassembler(ai.Arch(), ai.Mach(), endian, nil, options)
+}
+
+func newDisassembler(arch bfd.Architecture, mach uint64, endian bfd.Endian, abfd *bfd.File, options string) (*Disassembler, error) {
+	big := endian == bfd.ENDIAN_BIG
+	fn := C.disassembler(C.enum_bfd_architecture(arch), cbool(big), C.ulong(mach), (*C.bfd)(unsafe.Pointer(abfd)))
+	if fn == nil {
+		return nil, fmt.Errorf("can't disassemble for architecture %s", bfd.PrintableArchMach(arch, mach))
+	}
+
+	d := &Disassembler{
+		abfd: abfd,
+		fn:   fn,
+		info: (*C.disassemble_info)(C.calloc(1, C.sizeof_disassemble_info)),
+	}
+	C.initDisassembleInfo(d.info)
+	d.info.arch = C.enum_bfd_architecture(arch)
+	d.info.mach = C.ulong(mach)
+	d.info.octets_per_byte = 1
+	switch endian {
+	case bfd.ENDIAN_BIG:
+		d.info.endian = C.BFD_ENDIAN_BIG
+	case bfd.ENDIAN_LITTLE:
+		d.info.endian = C.BFD_ENDIAN_LITTLE
+	default:
+		d.info.endian = C.BFD_ENDIAN_UNKNOWN
+	}
+	d.info.display_endian = d.info.endian
+	d.info.endian_code = d.info.endian
+	if options != "" {
+		d.options = C.CString(options)
+		d.info.disassembler_options = d.options
+	}
+	C.disassemble_init_for_target(d.info)
+
+	dm.Lock()
+	dm.m[d.info] = d
+	dm.Unlock()
+	return d, nil
+}
+
+// SetSymbolFunc has branch and memory targets printed with the symbol they
+// fall in, as <name+offset> after the address. A nil f goes back to plain
+// addresses.
+func (d *Disassembler) SetSymbolFunc(f SymbolFunc) {
+	d.symbol = f
+	C.setPrintAddressFunc(d.info, C.int(cbool(f != nil)))
+}
+
+func (d *Disassembler) Close() {
+	dm.Lock()
+	delete(dm.m, d.info)
+	dm.Unlock()
+	C.free(unsafe.Pointer(d.options))
+	C.free(unsafe.Pointer(d.info))
+	d.info, d.options = nil, nil
+}
+
+// DisassembleSection disassembles the contents of a section of the bfd the
+// disassembler was made for.
+func (d *Disassembler) DisassembleSection(section *bfd.Section) ([]Instruction, error) {
+	if d.abfd == nil {
+		return nil, errors.New("disassembler is not bound to a file")
+	}
+	if section.Flags()&bfd.SEC_HAS_CONTENTS == 0 {
+		return nil, nil
+	}
+	code := make([]byte, bfd.GetSectionSize(section))
+	if err := bfd.GetSectionContents(d.abfd, section, code, 0); err != nil {
+		return nil, err
+	}
+
+	d.info.section = (*C.asection)(unsafe.Pointer(section))
+	defer func() { d.info.section = nil }()
+	return d.Disassemble(code, section.VMA())
+}
+
+// Disassemble decodes code as if it was loaded at vma. It works the same for
+// disassemblers made with New or NewRaw.
+func (d *Disassembler) Disassemble(code []byte, vma bfd.VMA) ([]Instruction, error) {
+	if len(code) == 0 {
+		return nil, nil
+	}
+
+	// the buffer is referenced from disassemble_info, which lives in C
+	// memory, so it can't point into a Go slice
+	buf := C.malloc(C.size_t(len(code)))
+	defer C.free(buf)
+	C.memcpy(buf, unsafe.Pointer(&code[0]), C.size_t(len(code)))
+	d.info.buffer = (*C.bfd_byte)(buf)
+	d.info.buffer_vma = C.bfd_vma(vma)
+	d.info.buffer_length = C.size_t(len(code))
+	defer func() { d.info.buffer = nil }()
+
+	var insns []Instruction
+	for off := 0; off < len(code); {
+		pc := vma + bfd.VMA(off)
+		d.text.Reset()
+		n := int(C.disassembleOne(d.fn, C.bfd_vma(pc), d.info))
+		if n <= 0 || off+n > len(code) {
+			text := strings.TrimSpace(d.text.String())
+			if text == "" {
+				text = "bad instruction"
+			}
+			return insns, fmt.Errorf("%#x: %s", pc, text)
+		}
+
+		insn := Instruction{
+			Address: pc,
+			Bytes:   code[off : off+n],
+			Text:    d.text.String(),
+		}
+		insn.Mnemonic, insn.Operands, insn.Comment = splitText(insn.Text)
+		if d.info.insn_info_valid != 0 {
+			insn.InfoValid = true
+			insn.Type = InsnType(d.info.insn_type)
+			insn.BranchDelay = int(d.info.branch_delay_insns)
+			insn.DataSize = int(d.info.data_size)
+			insn.Target = bfd.VMA(d.info.target)
+			insn.Target2 = bfd.VMA(d.info.target2)
+		}
+		insns = append(insns, insn)
+		off += n
+	}
+	return insns, nil
+}